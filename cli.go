@@ -1,24 +1,33 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/vsuhanov/scripto/entities"
 	"github.com/vsuhanov/scripto/internal/services"
+	"github.com/vsuhanov/scripto/internal/storage"
 	"github.com/vsuhanov/scripto/internal/templatex"
 )
 
 type cliPlaceholder struct {
-	Name          string   `json:"name"`
-	Label         string   `json:"label"`
-	DefaultValue  string   `json:"default_value,omitempty"`
-	AllowedValues []string `json:"allowed_values,omitempty"`
+	Name              string   `json:"name"`
+	Label             string   `json:"label"`
+	DefaultValue      string   `json:"default_value,omitempty"`
+	AllowedValues     []string `json:"allowed_values,omitempty"`
+	AllowedValuesFrom string   `json:"allowed_values_from,omitempty"`
+	Secret            bool     `json:"secret,omitempty"`
+	Type              string   `json:"type,omitempty"`
+	Pattern           string   `json:"pattern,omitempty"`
+	Position          int      `json:"position,omitempty"`
 }
 
 type cliScript struct {
@@ -28,15 +37,66 @@ type cliScript struct {
 	Scope        string           `json:"scope"`
 	FilePath     string           `json:"file_path"`
 	Archived     bool             `json:"archived"`
+	Pinned       bool             `json:"pinned"`
+	Target       string           `json:"target"`
+	ProjectFile  string           `json:"project_file,omitempty"`
+	Tags         []string         `json:"tags"`
+	Aliases      []string         `json:"aliases"`
+	AutoQuote    bool             `json:"auto_quote"`
+	CreatedAt    string           `json:"created_at,omitempty"`
+	UpdatedAt    string           `json:"updated_at,omitempty"`
 	Command      string           `json:"command"`
 	Placeholders []cliPlaceholder `json:"placeholders"`
 }
 
+type cliRevision struct {
+	Revision    int    `json:"revision"`
+	CreatedAt   string `json:"created_at"`
+	Hash        string `json:"hash"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Scope       string `json:"scope"`
+	Current     bool   `json:"current"`
+	Body        string `json:"body,omitempty"`
+}
+
+type cliTrashedScript struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Scope       string `json:"scope"`
+	Target      string `json:"target"`
+	ProjectFile string `json:"project_file,omitempty"`
+	DeletedAt   string `json:"deleted_at"`
+	PurgeAt     string `json:"purge_at,omitempty"`
+	Command     string `json:"command"`
+}
+
+type cliScopeVariables struct {
+	Scope     string            `json:"scope"`
+	Variables map[string]string `json:"variables"`
+}
+
+type cliScopeMoved struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Scripts int    `json:"scripts"`
+}
+
 type cliJSONInput struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
-	Scope       *string `json:"scope"`
-	Command     *string `json:"command"`
+	Name        *string   `json:"name"`
+	Description *string   `json:"description"`
+	Scope       *string   `json:"scope"`
+	Target      *string   `json:"target"`
+	Tags        *[]string `json:"tags"`
+	Aliases     *[]string `json:"aliases"`
+	AutoQuote   *bool     `json:"auto_quote"`
+	Command     *string   `json:"command"`
+}
+
+type cliRendered struct {
+	Command string   `json:"command"`
+	Missing []string `json:"missing"`
 }
 
 const cliUsage = `Usage: scripto cli <verb> [flags]
@@ -44,17 +104,34 @@ const cliUsage = `Usage: scripto cli <verb> [flags]
 Non-interactive script management with JSON output.
 
 Verbs:
-  list       List scripts (--all, --archived)
+  list       List scripts (--all, --archived, --tag)
   get        Show a single script (--id | --name)
-  add        Create a script (--name, --description, --scope, --command | --command-file | --stdin, --json)
-  edit       Update a script (--id | --name, --new-name, --description, --scope, --command | --command-file | --stdin, --json)
-  delete     Delete a script (--id | --name)
+  add        Create a script (--name, --description, --scope, --target, --tag, --alias, --auto-quote, --command | --command-file | --stdin, --json)
+  edit       Update a script (--id | --name, --new-name, --description, --scope, --target, --tag, --alias, --auto-quote, --command | --command-file | --stdin, --json)
+  render     Show the command a script runs (--id | --name, --set NAME=VALUE)
+  delete     Move a script to the trash (--id | --name)
   archive    Archive a script (--id | --name)
   unarchive  Unarchive a script (--id | --name)
+  pin        Pin a script to the top of lists and completion (--id | --name)
+  unpin      Unpin a script (--id | --name)
+  revisions  List saved revisions of a script (--id | --name, --body)
+  rollback   Restore an earlier revision (--id | --name, --revision)
+  trash      Manage deleted scripts (list | restore --id | purge --id | --all)
+  export     Write scripts to a JSON or tar bundle (--id, --name, --scope, --tag | --all, --output, --format)
+  import     Load scripts from a bundle (--file | --stdin, --map-scope, --on-conflict, --target)
+  scope-vars Manage variables shared by the scripts of a scope (list | resolve | set --scope NAME=VALUE... | unset --scope NAME...)
+  scope      Relocate scopes when projects move (list | move --from --to | merge --from --to)
 
 Run 'scripto cli <verb> --help' for verb-specific flags.
 All verbs print JSON to stdout; errors print {"error": "..."} with exit code 1.`
 
+// cliMutatingVerbs are the verbs that change scripts; expired trash is
+// purged before they run.
+var cliMutatingVerbs = map[string]bool{
+	"add": true, "edit": true, "delete": true, "archive": true, "unarchive": true,
+	"pin": true, "unpin": true, "rollback": true, "trash": true, "import": true,
+}
+
 func handleCli(container *services.Container, args []string) int {
 	if len(args) == 0 || args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
 		fmt.Println(cliUsage)
@@ -63,11 +140,16 @@ func handleCli(container *services.Container, args []string) int {
 		}
 		return 0
 	}
+	if cliMutatingVerbs[args[0]] {
+		purgeExpiredTrash(container)
+	}
 	switch args[0] {
 	case "list":
 		return cliList(container, args[1:])
 	case "get":
 		return cliGet(container, args[1:])
+	case "render":
+		return cliRender(container, args[1:])
 	case "add":
 		return cliAdd(container, args[1:])
 	case "edit":
@@ -78,8 +160,26 @@ func handleCli(container *services.Container, args []string) int {
 		return cliArchiveToggle(container, args[1:], true)
 	case "unarchive":
 		return cliArchiveToggle(container, args[1:], false)
+	case "pin":
+		return cliPinToggle(container, args[1:], true)
+	case "unpin":
+		return cliPinToggle(container, args[1:], false)
+	case "revisions":
+		return cliRevisions(container, args[1:])
+	case "rollback":
+		return cliRollback(container, args[1:])
+	case "trash":
+		return cliTrash(container, args[1:])
+	case "export":
+		return cliExport(container, args[1:])
+	case "import":
+		return cliImport(container, args[1:])
+	case "scope-vars":
+		return cliScopeVars(container, args[1:])
+	case "scope":
+		return cliScope(container, args[1:])
 	default:
-		return cliError(fmt.Sprintf("unknown verb '%s': expected one of list, get, add, edit, delete, archive, unarchive", args[0]))
+		return cliError(fmt.Sprintf("unknown verb '%s': expected one of list, get, render, add, edit, delete, archive, unarchive, pin, unpin, revisions, rollback, trash, export, import, scope-vars, scope", args[0]))
 	}
 }
 
@@ -98,6 +198,18 @@ func cliError(msg string) int {
 	return 1
 }
 
+// stringListFlag collects every occurrence of a repeatable flag.
+type stringListFlag []string
+
+func (f *stringListFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringListFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func newCliFlagSet(name string) *flag.FlagSet {
 	fs := flag.NewFlagSet(name, flag.ContinueOnError)
 	fs.SetOutput(io.Discard)
@@ -130,10 +242,15 @@ func toCliScript(s *entities.Script) cliScript {
 	if vars, err := templatex.ExtractVariables(command); err == nil {
 		for _, v := range vars {
 			placeholders = append(placeholders, cliPlaceholder{
-				Name:          v.Name,
-				Label:         v.Label,
-				DefaultValue:  v.DefaultValue,
-				AllowedValues: v.AllowedValues,
+				Name:              v.Name,
+				Label:             v.Label,
+				DefaultValue:      v.DefaultValue,
+				AllowedValues:     v.AllowedValues,
+				AllowedValuesFrom: v.AllowedValuesFrom,
+				Secret:            v.Secret,
+				Type:              v.Type,
+				Pattern:           v.Pattern,
+				Position:          v.Position,
 			})
 		}
 	}
@@ -143,6 +260,20 @@ func toCliScript(s *entities.Script) cliScript {
 		scope = s.OriginalScope
 	}
 
+	target := targetPersonal
+	if s.Source != "" {
+		target = targetProject
+	}
+
+	tags := s.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	aliases := s.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
 	return cliScript{
 		ID:           s.ID,
 		Name:         s.Name,
@@ -150,30 +281,69 @@ func toCliScript(s *entities.Script) cliScript {
 		Scope:        scope,
 		FilePath:     s.FilePath,
 		Archived:     s.Archived,
+		Pinned:       s.Pinned,
+		Target:       target,
+		ProjectFile:  s.Source,
+		Tags:         tags,
+		Aliases:      aliases,
+		AutoQuote:    s.AutoQuote,
+		CreatedAt:    formatCliTime(s.CreatedAt),
+		UpdatedAt:    formatCliTime(s.UpdatedAt),
 		Command:      command,
 		Placeholders: placeholders,
 	}
 }
 
+func formatCliTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+const (
+	targetPersonal = "personal"
+	targetProject  = "project"
+)
+
+func applyCliTarget(container *services.Container, script *entities.Script, target string) error {
+	switch target {
+	case targetPersonal:
+		script.Source = ""
+	case targetProject:
+		if script.Source == "" {
+			project := container.ScriptService.ProjectTarget()
+			script.Source = project.ConfigPath()
+			script.Scope = project.Root
+		}
+	default:
+		return fmt.Errorf("invalid target '%s': expected 'personal' or 'project'", target)
+	}
+	return nil
+}
+
 func resolveScript(container *services.Container, id, name string) (*entities.Script, error) {
 	if (id == "") == (name == "") {
 		return nil, fmt.Errorf("exactly one of --id or --name is required")
 	}
 
-	all, err := container.ScriptService.FindAllScopesScriptsWithArchived()
-	if err != nil {
-		return nil, err
-	}
-
 	if id != "" {
-		for _, s := range all {
-			if s.ID == id {
-				return s, nil
-			}
+		script, err := container.ScriptService.GetScript(id)
+		if err != nil {
+			return nil, fmt.Errorf("no script found with id '%s'", id)
 		}
-		return nil, fmt.Errorf("no script found with id '%s'", id)
+		return script, nil
 	}
 
+	match, err := resolveScriptByName(container, name)
+	if err != nil {
+		return nil, err
+	}
+	// Matches may carry a contextual scope; always hand back the stored script.
+	return container.ScriptService.GetScript(match.ID)
+}
+
+func resolveScriptByName(container *services.Container, name string) (*entities.Script, error) {
 	match, err := container.ScriptService.Match(name)
 	if err != nil {
 		return nil, err
@@ -187,8 +357,12 @@ func resolveScript(container *services.Container, id, name string) (*entities.Sc
 		return nil, err
 	}
 	if len(matches) == 0 {
+		all, err := container.ScriptService.FindAllScopesScriptsWithArchived()
+		if err != nil {
+			return nil, err
+		}
 		for _, s := range all {
-			if s.Name != "" && s.Name == name {
+			if services.MatchesName(s, name) {
 				matches = append(matches, s)
 			}
 		}
@@ -243,12 +417,17 @@ func cliList(container *services.Container, args []string) int {
 	fs := newCliFlagSet("list")
 	all := fs.Bool("all", false, "list scripts from every scope, not just those visible from the current directory")
 	archived := fs.Bool("archived", false, "list every script including archived ones")
+	var tags stringListFlag
+	fs.Var(&tags, "tag", "only list scripts with this tag (repeatable; every tag must match)")
 	if ok, code := cliParse(fs, args); !ok {
 		return code
 	}
+	wanted, err := services.NormalizeTags(tags)
+	if err != nil {
+		return cliError(err.Error())
+	}
 
 	var scripts []*entities.Script
-	var err error
 	if *archived {
 		scripts, err = container.ScriptService.FindAllScopesScriptsWithArchived()
 	} else if *all {
@@ -262,7 +441,9 @@ func cliList(container *services.Container, args []string) int {
 
 	out := []cliScript{}
 	for _, s := range scripts {
-		out = append(out, toCliScript(s))
+		if services.HasTags(s, wanted) {
+			out = append(out, toCliScript(s))
+		}
 	}
 	return printJSON(out)
 }
@@ -282,15 +463,54 @@ func cliGet(container *services.Container, args []string) int {
 	return printJSON(toCliScript(script))
 }
 
+func cliRender(container *services.Container, args []string) int {
+	fs := newCliFlagSet("render")
+	id := fs.String("id", "", "select script by id")
+	name := fs.String("name", "", "select script by name")
+	var sets stringListFlag
+	fs.Var(&sets, "set", "placeholder value as NAME=VALUE (repeatable)")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+
+	script, err := resolveScript(container, *id, *name)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	values := make(map[string]string)
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		if !ok || key == "" {
+			return cliError(fmt.Sprintf("invalid --set '%s': expected NAME=VALUE", set))
+		}
+		values[key] = value
+	}
+
+	command, missing, err := container.ExecutionService.Preview(script, values)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	if missing == nil {
+		missing = []string{}
+	}
+	return printJSON(cliRendered{Command: command, Missing: missing})
+}
+
 func cliAdd(container *services.Container, args []string) int {
 	fs := newCliFlagSet("add")
 	name := fs.String("name", "", "script name")
 	description := fs.String("description", "", "script description")
-	scope := fs.String("scope", "", "scope: 'global', an absolute directory path, or a glob pattern (default: current directory)")
+	scope := fs.String("scope", "", "scope: 'global', an absolute directory path, a glob pattern, git:host/org/repo, or marker:FILE (default: current directory)")
+	target := fs.String("target", targetPersonal, "where to save the script: 'personal' or 'project' (the nearest .scripto/scripts.json, created in the current directory if none exists)")
 	command := fs.String("command", "", "command body as a string")
 	commandFile := fs.String("command-file", "", "read command body from a file")
 	useStdin := fs.Bool("stdin", false, "read command body from stdin")
-	useJSON := fs.Bool("json", false, "read {name,description,scope,command} JSON object from stdin; explicit flags override")
+	var tags stringListFlag
+	fs.Var(&tags, "tag", "tag the script (repeatable)")
+	var aliases stringListFlag
+	fs.Var(&aliases, "alias", "another name the script can be run by (repeatable)")
+	autoQuote := fs.Bool("auto-quote", false, "shell-quote every placeholder value when the script runs")
+	useJSON := fs.Bool("json", false, "read {name,description,scope,target,tags,aliases,auto_quote,command} JSON object from stdin; explicit flags override")
 	if ok, code := cliParse(fs, args); !ok {
 		return code
 	}
@@ -301,6 +521,8 @@ func cliAdd(container *services.Container, args []string) int {
 	script := container.ScriptService.CreateEmptyScript()
 	var commandBody string
 	haveCommand := false
+	targetValue := *target
+	scopeSet := false
 
 	if *useJSON {
 		payload, err := readJSONInput()
@@ -314,7 +536,20 @@ func cliAdd(container *services.Container, args []string) int {
 			script.Description = *payload.Description
 		}
 		if payload.Scope != nil {
-			script.Scope = *payload.Scope
+			script.Scope = services.ExpandScope(*payload.Scope)
+			scopeSet = true
+		}
+		if payload.Target != nil {
+			targetValue = *payload.Target
+		}
+		if payload.Tags != nil {
+			script.Tags = *payload.Tags
+		}
+		if payload.Aliases != nil {
+			script.Aliases = *payload.Aliases
+		}
+		if payload.AutoQuote != nil {
+			script.AutoQuote = *payload.AutoQuote
 		}
 		if payload.Command != nil {
 			commandBody = *payload.Command
@@ -329,10 +564,26 @@ func cliAdd(container *services.Container, args []string) int {
 		case "description":
 			script.Description = *description
 		case "scope":
-			script.Scope = *scope
+			script.Scope = services.ExpandScope(*scope)
+			scopeSet = true
+		case "target":
+			targetValue = *target
+		case "tag":
+			script.Tags = tags
+		case "alias":
+			script.Aliases = aliases
+		case "auto-quote":
+			script.AutoQuote = *autoQuote
 		}
 	})
 
+	if targetValue == targetProject && scopeSet {
+		return cliError("--scope cannot be combined with --target project: project scripts belong to the project directory")
+	}
+	if err := applyCliTarget(container, script, targetValue); err != nil {
+		return cliError(err.Error())
+	}
+
 	if body, ok, err := readCommandInput(*command, *commandFile, *useStdin); err != nil {
 		return cliError(err.Error())
 	} else if ok {
@@ -359,11 +610,17 @@ func cliEdit(container *services.Container, args []string) int {
 	name := fs.String("name", "", "select script by name")
 	newName := fs.String("new-name", "", "rename the script")
 	description := fs.String("description", "", "new description (omit to preserve, pass \"\" to clear)")
-	scope := fs.String("scope", "", "new scope: 'global', an absolute directory path, or a glob pattern")
+	scope := fs.String("scope", "", "new scope: 'global', an absolute directory path, a glob pattern, git:host/org/repo, or marker:FILE")
+	target := fs.String("target", "", "move the script to 'personal' or 'project' storage")
 	command := fs.String("command", "", "new command body as a string")
 	commandFile := fs.String("command-file", "", "read new command body from a file")
 	useStdin := fs.Bool("stdin", false, "read new command body from stdin")
-	useJSON := fs.Bool("json", false, "read {name,description,scope,command} JSON object from stdin; only present keys are applied")
+	var tags stringListFlag
+	fs.Var(&tags, "tag", "replace the script's tags (repeatable; pass \"\" to clear)")
+	var aliases stringListFlag
+	fs.Var(&aliases, "alias", "replace the script's aliases (repeatable; pass \"\" to clear)")
+	autoQuote := fs.Bool("auto-quote", false, "shell-quote every placeholder value when the script runs (--auto-quote=false to turn off)")
+	useJSON := fs.Bool("json", false, "read {name,description,scope,target,tags,aliases,auto_quote,command} JSON object from stdin; only present keys are applied")
 	if ok, code := cliParse(fs, args); !ok {
 		return code
 	}
@@ -380,6 +637,8 @@ func cliEdit(container *services.Container, args []string) int {
 	updated.OriginalScope = ""
 	var commandBody string
 	haveCommand := false
+	targetValue := ""
+	scopeSet := false
 
 	if *useJSON {
 		payload, err := readJSONInput()
@@ -393,7 +652,20 @@ func cliEdit(container *services.Container, args []string) int {
 			updated.Description = *payload.Description
 		}
 		if payload.Scope != nil {
-			updated.Scope = *payload.Scope
+			updated.Scope = services.ExpandScope(*payload.Scope)
+			scopeSet = true
+		}
+		if payload.Target != nil {
+			targetValue = *payload.Target
+		}
+		if payload.Tags != nil {
+			updated.Tags = *payload.Tags
+		}
+		if payload.Aliases != nil {
+			updated.Aliases = *payload.Aliases
+		}
+		if payload.AutoQuote != nil {
+			updated.AutoQuote = *payload.AutoQuote
 		}
 		if payload.Command != nil {
 			commandBody = *payload.Command
@@ -408,10 +680,28 @@ func cliEdit(container *services.Container, args []string) int {
 		case "description":
 			updated.Description = *description
 		case "scope":
-			updated.Scope = *scope
+			updated.Scope = services.ExpandScope(*scope)
+			scopeSet = true
+		case "target":
+			targetValue = *target
+		case "tag":
+			updated.Tags = tags
+		case "alias":
+			updated.Aliases = aliases
+		case "auto-quote":
+			updated.AutoQuote = *autoQuote
 		}
 	})
 
+	if scopeSet && (targetValue == targetProject || (targetValue == "" && updated.Source != "")) {
+		return cliError("project scripts belong to the project directory; use --target personal to change the scope")
+	}
+	if targetValue != "" {
+		if err := applyCliTarget(container, &updated, targetValue); err != nil {
+			return cliError(err.Error())
+		}
+	}
+
 	if body, ok, err := readCommandInput(*command, *commandFile, *useStdin); err != nil {
 		return cliError(err.Error())
 	} else if ok {
@@ -454,7 +744,7 @@ func cliDelete(container *services.Container, args []string) int {
 	if err := container.ScriptService.DeleteScript(script); err != nil {
 		return cliError(err.Error())
 	}
-	return printJSON(map[string]any{"deleted": true, "id": script.ID})
+	return printJSON(map[string]any{"deleted": true, "trashed": true, "id": script.ID})
 }
 
 func cliArchiveToggle(container *services.Container, args []string, archive bool) int {
@@ -483,3 +773,541 @@ func cliArchiveToggle(container *services.Container, args []string, archive bool
 	}
 	return printJSON(map[string]any{"archived": archive, "id": script.ID})
 }
+
+func cliPinToggle(container *services.Container, args []string, pin bool) int {
+	verb := "pin"
+	if !pin {
+		verb = "unpin"
+	}
+	fs := newCliFlagSet(verb)
+	id := fs.String("id", "", "select script by id")
+	name := fs.String("name", "", "select script by name")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+
+	script, err := resolveScript(container, *id, *name)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	if pin {
+		err = container.ScriptService.PinScript(script)
+	} else {
+		err = container.ScriptService.UnpinScript(script)
+	}
+	if err != nil {
+		return cliError(err.Error())
+	}
+	return printJSON(map[string]any{"pinned": pin, "id": script.ID})
+}
+
+func cliRevisions(container *services.Container, args []string) int {
+	fs := newCliFlagSet("revisions")
+	id := fs.String("id", "", "select script by id")
+	name := fs.String("name", "", "select script by name")
+	withBody := fs.Bool("body", false, "include the body of every revision")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+
+	script, err := resolveScript(container, *id, *name)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	revisions, err := container.ScriptService.GetRevisions(script.ID)
+	if err != nil {
+		return cliError(err.Error())
+	}
+
+	out := []cliRevision{}
+	for i, r := range revisions {
+		rev := cliRevision{
+			Revision:    r.Revision,
+			CreatedAt:   r.CreatedAt.Format(time.RFC3339),
+			Hash:        r.BodyHash,
+			Name:        r.Metadata.Name,
+			Description: r.Metadata.Description,
+			Scope:       r.Metadata.Scope,
+			Current:     i == 0,
+		}
+		if *withBody {
+			rev.Body = r.Body
+		}
+		out = append(out, rev)
+	}
+	return printJSON(out)
+}
+
+func cliRollback(container *services.Container, args []string) int {
+	fs := newCliFlagSet("rollback")
+	id := fs.String("id", "", "select script by id")
+	name := fs.String("name", "", "select script by name")
+	revision := fs.Int("revision", 0, "revision number to restore (see 'scripto cli revisions')")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+	if *revision <= 0 {
+		return cliError("--revision is required")
+	}
+
+	script, err := resolveScript(container, *id, *name)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	updated, err := container.ScriptService.RollbackScript(script, *revision)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	return printJSON(toCliScript(updated))
+}
+
+func cliTrash(container *services.Container, args []string) int {
+	if len(args) == 0 {
+		return cliError("trash requires a subcommand: list, restore or purge")
+	}
+	switch args[0] {
+	case "list":
+		return cliTrashList(container, args[1:])
+	case "restore":
+		return cliTrashRestore(container, args[1:])
+	case "purge":
+		return cliTrashPurge(container, args[1:])
+	default:
+		return cliError(fmt.Sprintf("unknown trash subcommand '%s': expected one of list, restore, purge", args[0]))
+	}
+}
+
+func cliTrashList(container *services.Container, args []string) int {
+	fs := newCliFlagSet("trash list")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+
+	trash, err := container.ScriptService.GetTrash()
+	if err != nil {
+		return cliError(err.Error())
+	}
+
+	retention := services.TrashRetention()
+	out := []cliTrashedScript{}
+	for _, t := range trash {
+		target := targetPersonal
+		if t.Script.Source != "" {
+			target = targetProject
+		}
+		entry := cliTrashedScript{
+			ID:          t.Script.ID,
+			Name:        t.Script.Name,
+			Description: t.Script.Description,
+			Scope:       t.Script.Scope,
+			Target:      target,
+			ProjectFile: t.Script.Source,
+			DeletedAt:   t.DeletedAt.Format(time.RFC3339),
+			Command:     t.Body,
+		}
+		if retention > 0 {
+			entry.PurgeAt = t.DeletedAt.Add(retention).Format(time.RFC3339)
+		}
+		out = append(out, entry)
+	}
+	return printJSON(out)
+}
+
+func cliTrashRestore(container *services.Container, args []string) int {
+	fs := newCliFlagSet("trash restore")
+	id := fs.String("id", "", "id of the trashed script (see 'scripto cli trash list')")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+	if *id == "" {
+		return cliError("--id is required")
+	}
+
+	script, err := container.ScriptService.RestoreScript(*id)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	return printJSON(toCliScript(script))
+}
+
+func cliTrashPurge(container *services.Container, args []string) int {
+	fs := newCliFlagSet("trash purge")
+	id := fs.String("id", "", "id of the trashed script to remove permanently")
+	all := fs.Bool("all", false, "empty the whole trash")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+	if (*id == "") == !*all {
+		return cliError("exactly one of --id or --all is required")
+	}
+
+	if *all {
+		purged, err := container.ScriptService.PurgeTrash()
+		if err != nil {
+			return cliError(err.Error())
+		}
+		return printJSON(map[string]any{"purged": purged})
+	}
+	trashed, err := container.ScriptService.GetTrashedScript(*id)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	if err := container.ScriptService.PurgeScript(trashed.Script); err != nil {
+		return cliError(err.Error())
+	}
+	return printJSON(map[string]any{"purged": 1, "id": *id})
+}
+
+func cliExport(container *services.Container, args []string) int {
+	fs := newCliFlagSet("export")
+	var ids, names, scopes, tags stringListFlag
+	fs.Var(&ids, "id", "export the script with this id (repeatable)")
+	fs.Var(&names, "name", "export the script with this name (repeatable)")
+	fs.Var(&scopes, "scope", "export every script in this scope (repeatable)")
+	fs.Var(&tags, "tag", "export every script with this tag (repeatable)")
+	all := fs.Bool("all", false, "export every script, including archived ones")
+	output := fs.String("output", "", "write the bundle to this file instead of stdout")
+	format := fs.String("format", "", "bundle format: json or tar (default: from --output extension, else json)")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+
+	if *format == "" {
+		*format = storage.BundleFormatJSON
+		if strings.HasSuffix(*output, ".tar") {
+			*format = storage.BundleFormatTar
+		}
+	}
+	if *format != storage.BundleFormatJSON && *format != storage.BundleFormatTar {
+		return cliError(fmt.Sprintf("invalid format '%s': expected json or tar", *format))
+	}
+	if *format == storage.BundleFormatTar && *output == "" {
+		return cliError("--output is required for tar bundles")
+	}
+	if !*all && len(ids)+len(names)+len(scopes)+len(tags) == 0 {
+		return cliError("select scripts with --id, --name, --scope, --tag or --all")
+	}
+
+	scripts, err := selectExportScripts(container, *all, ids, names, scopes, tags)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	bundle, err := container.ScriptService.ExportBundle(scripts)
+	if err != nil {
+		return cliError(err.Error())
+	}
+
+	if *output == "" {
+		if err := storage.WriteBundle(os.Stdout, bundle, *format); err != nil {
+			return cliError(err.Error())
+		}
+		return 0
+	}
+
+	var buf bytes.Buffer
+	if err := storage.WriteBundle(&buf, bundle, *format); err != nil {
+		return cliError(err.Error())
+	}
+	if err := storage.WriteFileAtomic(*output, buf.Bytes(), 0644); err != nil {
+		return cliError(fmt.Sprintf("failed to write bundle: %v", err))
+	}
+	return printJSON(map[string]any{"exported": len(bundle.Scripts), "output": *output, "format": *format})
+}
+
+func selectExportScripts(container *services.Container, all bool, ids, names, scopes, tags []string) ([]*entities.Script, error) {
+	available, err := container.ScriptService.FindAllScopesScriptsWithArchived()
+	if err != nil {
+		return nil, err
+	}
+	if all {
+		return available, nil
+	}
+
+	selected := make(map[string]bool)
+	for _, id := range ids {
+		script, err := resolveScript(container, id, "")
+		if err != nil {
+			return nil, err
+		}
+		selected[script.ID] = true
+	}
+	for _, name := range names {
+		script, err := resolveScript(container, "", name)
+		if err != nil {
+			return nil, err
+		}
+		selected[script.ID] = true
+	}
+	for _, tag := range tags {
+		tagged, err := container.ScriptService.ScriptIDsWithTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		for id := range tagged {
+			selected[id] = true
+		}
+	}
+	scopeSet := make(map[string]bool)
+	for _, scope := range scopes {
+		scopeSet[scope] = true
+	}
+
+	var scripts []*entities.Script
+	for _, script := range available {
+		scope := script.Scope
+		if script.OriginalScope != "" {
+			scope = script.OriginalScope
+		}
+		if selected[script.ID] || scopeSet[scope] {
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts, nil
+}
+
+func cliImport(container *services.Container, args []string) int {
+	fs := newCliFlagSet("import")
+	file := fs.String("file", "", "read the bundle from this file")
+	useStdin := fs.Bool("stdin", false, "read the bundle from stdin")
+	var mappings stringListFlag
+	fs.Var(&mappings, "map-scope", "rewrite scopes starting with /old to /new, as /old=/new (repeatable)")
+	onConflict := fs.String("on-conflict", services.ImportSkip, "when a script with the same name exists in the scope: skip, overwrite or rename")
+	target := fs.String("target", targetPersonal, "import into personal storage or the project's .scripto/scripts.json: personal or project")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+	if (*file == "") == !*useStdin {
+		return cliError("exactly one of --file or --stdin is required")
+	}
+
+	var reader io.Reader = os.Stdin
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return cliError(fmt.Sprintf("failed to open bundle: %v", err))
+		}
+		defer f.Close()
+		reader = f
+	}
+	bundle, err := storage.ReadBundle(reader)
+	if err != nil {
+		return cliError(err.Error())
+	}
+
+	scopeMap, err := services.ParseScopeMapping(mappings)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	opts := services.ImportOptions{ScopeMap: scopeMap, OnConflict: *onConflict}
+	switch *target {
+	case targetPersonal:
+	case targetProject:
+		opts.Source = container.ScriptService.ProjectTarget().ConfigPath()
+	default:
+		return cliError(fmt.Sprintf("invalid target '%s': expected 'personal' or 'project'", *target))
+	}
+
+	results, err := container.ScriptService.ImportBundle(bundle, opts)
+	if err != nil {
+		return cliError(err.Error())
+	}
+
+	type importedEntry struct {
+		Action     string `json:"action"`
+		BundleName string `json:"bundle_name"`
+		cliScript
+	}
+	out := []importedEntry{}
+	for _, r := range results {
+		out = append(out, importedEntry{Action: r.Action, BundleName: r.BundleName, cliScript: toCliScript(r.Script)})
+	}
+	return printJSON(out)
+}
+
+func cliScopeVars(container *services.Container, args []string) int {
+	if len(args) == 0 {
+		return cliError("scope-vars requires a subcommand: list, resolve, set or unset")
+	}
+	switch args[0] {
+	case "list":
+		return cliScopeVarsList(container, args[1:])
+	case "resolve":
+		return cliScopeVarsResolve(container, args[1:])
+	case "set":
+		return cliScopeVarsSet(container, args[1:])
+	case "unset":
+		return cliScopeVarsUnset(container, args[1:])
+	default:
+		return cliError(fmt.Sprintf("unknown scope-vars subcommand '%s': expected one of list, resolve, set, unset", args[0]))
+	}
+}
+
+func cliScopeVarsList(container *services.Container, args []string) int {
+	fs := newCliFlagSet("scope-vars list")
+	scope := fs.String("scope", "", "only show this scope")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+
+	scopes := container.ScriptService.ScopesWithVariables()
+	if *scope != "" {
+		scopes = []string{*scope}
+	}
+	out := []cliScopeVariables{}
+	for _, s := range scopes {
+		out = append(out, cliScopeVariables{Scope: s, Variables: container.ScriptService.ScopeVariables(s)})
+	}
+	return printJSON(out)
+}
+
+func cliScopeVarsResolve(container *services.Container, args []string) int {
+	fs := newCliFlagSet("scope-vars resolve")
+	dir := fs.String("dir", "", "directory to resolve for (default: current directory)")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+	if *dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return cliError(err.Error())
+		}
+		*dir = cwd
+	}
+	return printJSON(cliScopeVariables{Scope: *dir, Variables: container.ScriptService.ResolveScopeVariables(*dir)})
+}
+
+func cliScopeVarsSet(container *services.Container, args []string) int {
+	fs := newCliFlagSet("scope-vars set")
+	scope := fs.String("scope", "", "scope: 'global', an absolute directory path, a glob pattern, git:host/org/repo, or marker:FILE (default: current directory)")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+	scopeValue, err := cliScopeOrCwd(*scope)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	if fs.NArg() == 0 {
+		return cliError("expected one or more NAME=VALUE arguments")
+	}
+
+	for _, pair := range fs.Args() {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return cliError(fmt.Sprintf("invalid variable '%s': expected NAME=VALUE", pair))
+		}
+		if err := container.ScriptService.SetScopeVariable(scopeValue, name, value); err != nil {
+			return cliError(err.Error())
+		}
+	}
+	return printJSON(cliScopeVariables{Scope: scopeValue, Variables: container.ScriptService.ScopeVariables(scopeValue)})
+}
+
+func cliScopeVarsUnset(container *services.Container, args []string) int {
+	fs := newCliFlagSet("scope-vars unset")
+	scope := fs.String("scope", "", "scope to remove the variables from (default: current directory)")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+	scopeValue, err := cliScopeOrCwd(*scope)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	if fs.NArg() == 0 {
+		return cliError("expected one or more variable names")
+	}
+
+	for _, name := range fs.Args() {
+		if err := container.ScriptService.UnsetScopeVariable(scopeValue, name); err != nil {
+			return cliError(err.Error())
+		}
+	}
+	return printJSON(cliScopeVariables{Scope: scopeValue, Variables: container.ScriptService.ScopeVariables(scopeValue)})
+}
+
+func cliScopeOrCwd(scope string) (string, error) {
+	if scope != "" {
+		return services.ExpandScope(scope), nil
+	}
+	return os.Getwd()
+}
+
+func cliScope(container *services.Container, args []string) int {
+	if len(args) == 0 {
+		return cliError("scope requires a subcommand: list, move or merge")
+	}
+	switch args[0] {
+	case "list":
+		return cliScopeList(container, args[1:])
+	case "move":
+		return cliScopeRelocate(container, args[1:], false)
+	case "merge":
+		return cliScopeRelocate(container, args[1:], true)
+	default:
+		return cliError(fmt.Sprintf("unknown scope subcommand '%s': expected one of list, move, merge", args[0]))
+	}
+}
+
+func cliScopeList(container *services.Container, args []string) int {
+	fs := newCliFlagSet("scope list")
+	missing := fs.Bool("missing", false, "only list directory scopes whose directory no longer exists")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+
+	scopes, err := container.ScriptService.ListScopes()
+	if err != nil {
+		return cliError(err.Error())
+	}
+	out := []services.ScopeInfo{}
+	for _, scope := range scopes {
+		if *missing && scope.Exists {
+			continue
+		}
+		out = append(out, scope)
+	}
+	return printJSON(out)
+}
+
+func cliScopeRelocate(container *services.Container, args []string, merge bool) int {
+	name := "scope move"
+	if merge {
+		name = "scope merge"
+	}
+	fs := newCliFlagSet(name)
+	from := fs.String("from", "", "scope to move the scripts out of (required)")
+	to := fs.String("to", "", "scope to move the scripts to (required)")
+	if ok, code := cliParse(fs, args); !ok {
+		return code
+	}
+	if *from == "" || *to == "" {
+		return cliError("both --from and --to are required")
+	}
+	fromScope, err := cliScopeArg(*from)
+	if err != nil {
+		return cliError(err.Error())
+	}
+	toScope, err := cliScopeArg(*to)
+	if err != nil {
+		return cliError(err.Error())
+	}
+
+	var moved int
+	if merge {
+		moved, err = container.ScriptService.MergeScope(fromScope, toScope)
+	} else {
+		moved, err = container.ScriptService.MoveScope(fromScope, toScope)
+	}
+	if err != nil {
+		return cliError(err.Error())
+	}
+	return printJSON(cliScopeMoved{From: fromScope, To: toScope, Scripts: moved})
+}
+
+// cliScopeArg makes a relative or ~ directory scope absolute, so a scope can
+// be given as it was typed in the shell.
+func cliScopeArg(scope string) (string, error) {
+	if scope == "global" || services.IsMatchedScope(scope) {
+		return scope, nil
+	}
+	return filepath.Abs(services.ExpandScope(scope))
+}
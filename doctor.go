@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/vsuhanov/scripto/internal/services"
+	"github.com/vsuhanov/scripto/internal/tui/colors"
+)
+
+const doctorUsage = `Usage: scripto doctor [--fix] [--json]
+
+Checks the script store for problems: scripts.json entries that were never
+imported, missing or duplicate IDs in project files, missing command files,
+command files no script uses, duplicate names, stale or missing shortcuts and
+execution history that points at removed scripts.
+
+Flags:
+  --fix    repair what can be repaired safely (nothing is deleted)
+  --json   print the report as JSON`
+
+type doctorReport struct {
+	Issues    []*services.DoctorIssue `json:"issues"`
+	Fixed     int                     `json:"fixed"`
+	Remaining int                     `json:"remaining"`
+}
+
+// handleDoctor runs the store checks and returns the process exit code: 0 when
+// no problems remain, 1 otherwise.
+func handleDoctor(container *services.Container, args []string) int {
+	fix := false
+	asJSON := false
+	for _, arg := range args {
+		switch arg {
+		case "--fix":
+			fix = true
+		case "--json":
+			asJSON = true
+		case "--help", "-h":
+			fmt.Println(doctorUsage)
+			return 0
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown flag '%s'\n\n%s\n", arg, doctorUsage)
+			return 1
+		}
+	}
+
+	issues, err := container.ScriptService.RunDoctor(fix)
+	if err != nil {
+		if asJSON {
+			return cliError(err.Error())
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	report := doctorReport{Issues: issues}
+	if report.Issues == nil {
+		report.Issues = []*services.DoctorIssue{}
+	}
+	for _, issue := range issues {
+		if issue.Fixed {
+			report.Fixed++
+		} else {
+			report.Remaining++
+		}
+	}
+
+	if asJSON {
+		printJSON(report)
+	} else {
+		printDoctorReport(report, fix)
+	}
+
+	if report.Remaining > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printDoctorReport(report doctorReport, fix bool) {
+	okStyle := lipgloss.NewStyle().Foreground(colors.Success).Bold(true)
+	problemStyle := lipgloss.NewStyle().Foreground(colors.Error).Bold(true)
+	detailStyle := lipgloss.NewStyle().Foreground(colors.MutedText)
+
+	if len(report.Issues) == 0 {
+		fmt.Println(okStyle.Render("✓") + " No problems found")
+		return
+	}
+
+	fixable := 0
+	for _, issue := range report.Issues {
+		marker := problemStyle.Render("✗")
+		if issue.Fixed {
+			marker = okStyle.Render("✓")
+		}
+		fmt.Printf("%s %s %s\n", marker, detailStyle.Render("["+issue.Kind+"]"), issue.Message)
+		if issue.Path != "" {
+			fmt.Printf("    %s\n", detailStyle.Render(issue.Path))
+		}
+		switch {
+		case issue.Fixed:
+			fmt.Printf("    %s\n", detailStyle.Render("fixed: "+issue.Fix))
+		case issue.FixError != "":
+			fmt.Printf("    %s\n", problemStyle.Render("fix failed: "+issue.FixError))
+		case issue.Fix != "":
+			fmt.Printf("    %s\n", detailStyle.Render("fix: "+issue.Fix))
+			fixable++
+		default:
+			fmt.Printf("    %s\n", detailStyle.Render("needs manual attention"))
+		}
+	}
+
+	fmt.Println()
+	if fix {
+		fmt.Printf("Fixed %d of %s.\n", report.Fixed, problemCount(len(report.Issues)))
+	} else if fixable > 0 {
+		fmt.Printf("Found %s; run 'scripto doctor --fix' to repair %d.\n", problemCount(len(report.Issues)), fixable)
+	} else {
+		fmt.Printf("Found %s.\n", problemCount(len(report.Issues)))
+	}
+}
+
+func problemCount(n int) string {
+	if n == 1 {
+		return "1 problem"
+	}
+	return fmt.Sprintf("%d problems", n)
+}
@@ -1,5 +1,7 @@
 package entities
 
+import "time"
+
 type Script struct {
 	ID                         string `json:"id"`
 	Name                       string `json:"name"`
@@ -7,5 +9,14 @@ type Script struct {
 	FilePath                   string `json:"file_path,omitempty"`
 	Scope                      string `json:"scope"`
 	Archived bool `json:"archived,omitempty"`
+	Pinned                     bool `json:"-"`
+	Tags                       []string `json:"tags,omitempty"`
+	Aliases                    []string `json:"aliases,omitempty"`
+	// AutoQuote shell-quotes every placeholder value when the script renders.
+	AutoQuote                  bool `json:"auto_quote,omitempty"`
 	OriginalScope              string `json:"-"`
+	Source                     string `json:"-"`
+	Version                    int    `json:"-"`
+	CreatedAt                  time.Time `json:"-"`
+	UpdatedAt                  time.Time `json:"-"`
 }
@@ -18,10 +18,14 @@ type ProcessResult struct {
 
 type ArgumentProcessor struct {
 	script *entities.Script
+	// options say how the script template is rendered.
+	options templatex.Options
 }
 
-func NewArgumentProcessor(script *entities.Script) *ArgumentProcessor {
-	return &ArgumentProcessor{script: script}
+// NewArgumentProcessor returns a processor for script that renders its
+// template with options.
+func NewArgumentProcessor(script *entities.Script, options templatex.Options) *ArgumentProcessor {
+	return &ArgumentProcessor{script: script, options: options}
 }
 
 func (p *ArgumentProcessor) getCommandContent() (string, error) {
@@ -51,6 +55,9 @@ func (p *ArgumentProcessor) ProcessArguments(args []string) (*ProcessResult, err
 	provided := p.parseProvidedArguments(args)
 	log.Printf("args: %s", args)
 	log.Printf("providedValues: %s", provided)
+	if err := templatex.AssignPositional(metas, provided.Named, provided.Positional); err != nil {
+		return nil, err
+	}
 
 	values := make(map[string]string)
 	for _, meta := range metas {
@@ -75,7 +82,7 @@ func (p *ArgumentProcessor) ProcessArguments(args []string) (*ProcessResult, err
 	}
 
 	if len(missingArgs) == 0 {
-		finalCommand, err := templatex.Execute(content, values)
+		finalCommand, err := templatex.Render(content, values, p.options)
 		if err != nil {
 			return nil, fmt.Errorf("failed to execute template: %w", err)
 		}
@@ -124,7 +131,7 @@ func (p *ArgumentProcessor) BuildPreviewCommand(values map[string]string) string
 	if err != nil {
 		return ""
 	}
-	result, err := templatex.Execute(content, values)
+	result, err := templatex.RenderRedacted(content, values, p.options)
 	if err != nil {
 		return content
 	}
@@ -68,6 +68,11 @@ func (m *ScriptMatcher) Match(input string) (*entities.Script, error) {
 		if script.Name != "" && script.Name == input {
 			return script, nil
 		}
+		for _, alias := range script.Aliases {
+			if alias == input {
+				return script, nil
+			}
+		}
 	}
 
 	return nil, nil
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const defaultAllowedValuesTTL = 5 * time.Minute
+
+var (
+	allowedValuesTimeout = 10 * time.Second
+	// allowedValuesWaitDelay is how long a timed-out command may take to
+	// close its output once killed.
+	allowedValuesWaitDelay = time.Second
+)
+
+// AllowedValuesService runs the commands of allowedValuesFrom annotations and
+// caches their output per working directory.
+type AllowedValuesService struct {
+	db *sql.DB
+}
+
+func NewAllowedValuesService(db *sql.DB) *AllowedValuesService {
+	return &AllowedValuesService{db: db}
+}
+
+// AllowedValuesTTL returns how long the output of an allowedValuesFrom command
+// is reused. Zero runs the command every time.
+func AllowedValuesTTL() time.Duration {
+	if value := os.Getenv("SCRIPTO_ALLOWED_VALUES_TTL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			log.Printf("Warning: invalid SCRIPTO_ALLOWED_VALUES_TTL %q, using %s", value, defaultAllowedValuesTTL)
+		} else {
+			return parsed
+		}
+	}
+	return defaultAllowedValuesTTL
+}
+
+// AllowedValues returns the non-empty output lines of command run with sh in
+// dir, from the cache while it is fresh.
+func (s *AllowedValuesService) AllowedValues(command, dir string) ([]string, error) {
+	ttl := AllowedValuesTTL()
+	if ttl > 0 {
+		if values, ok := s.cached(command, dir, ttl); ok {
+			return values, nil
+		}
+	}
+
+	values, err := runAllowedValuesCommand(command, dir)
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		s.store(command, dir, values)
+	}
+	return values, nil
+}
+
+func (s *AllowedValuesService) cached(command, dir string, ttl time.Duration) ([]string, bool) {
+	var data string
+	var fetchedAt int64
+	err := s.db.QueryRow(
+		"SELECT allowed_values, fetched_at FROM allowed_values_cache WHERE command = ? AND working_directory = ?",
+		command, dir,
+	).Scan(&data, &fetchedAt)
+	if err != nil {
+		return nil, false
+	}
+	if time.Since(time.Unix(fetchedAt, 0)) > ttl {
+		return nil, false
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(data), &values); err != nil {
+		return nil, false
+	}
+	return values, true
+}
+
+func (s *AllowedValuesService) store(command, dir string, values []string) {
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return
+	}
+	if _, err := s.db.Exec(
+		`INSERT INTO allowed_values_cache (command, working_directory, allowed_values, fetched_at)
+		 VALUES (?, ?, ?, ?)
+		 ON CONFLICT (command, working_directory) DO UPDATE SET allowed_values = excluded.allowed_values, fetched_at = excluded.fetched_at`,
+		command, dir, string(encoded), time.Now().Unix(),
+	); err != nil {
+		log.Printf("Warning: failed to cache allowed values: %v", err)
+	}
+}
+
+func runAllowedValuesCommand(command, dir string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), allowedValuesTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	// A timeout kills the whole process group, not just sh, so children
+	// holding the output open, like sleep in "sleep 100 | cat", go too.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) }
+	cmd.WaitDelay = allowedValuesWaitDelay
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("'%s' timed out after %s", command, allowedValuesTimeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("'%s' failed: %s", command, firstLine(msg))
+		}
+		return nil, fmt.Errorf("'%s' failed: %w", command, err)
+	}
+
+	var values []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("'%s' printed no values", command)
+	}
+	return values, nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
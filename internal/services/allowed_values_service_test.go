@@ -0,0 +1,32 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunAllowedValuesCommand(t *testing.T) {
+	values, err := runAllowedValuesCommand("printf 'dev\\n\\n  prod  \\n'", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(values, ",") != "dev,prod" {
+		t.Errorf("expected the non-empty lines, got %q", values)
+	}
+}
+
+func TestRunAllowedValuesCommand_Timeout(t *testing.T) {
+	timeout := allowedValuesTimeout
+	allowedValuesTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { allowedValuesTimeout = timeout })
+
+	start := time.Now()
+	_, err := runAllowedValuesCommand("sleep 30 | cat", t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the timeout to stop the command, took %s", elapsed)
+	}
+}
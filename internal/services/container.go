@@ -1,8 +1,11 @@
 package services
 
 import (
+	"fmt"
 	"log"
 	"os"
+
+	"github.com/vsuhanov/scripto/internal/storage"
 )
 
 type Container struct {
@@ -11,31 +14,36 @@ type Container struct {
 	TerminalService        *TerminalService
 	HistoryService         *HistoryService
 	ExecutionHistoryService *ExecutionHistoryService
+	VaultService            *VaultService
+	AllowedValuesService    *AllowedValuesService
 }
 
 func NewContainer() (*Container, error) {
-	scriptService, err := NewScriptService()
+	db, err := storage.OpenSQLite()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+
+	scriptService, err := NewScriptService(db)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	log.Printf("SCRIPTO_CMD_FD=%v", os.Getenv("SCRIPTO_CMD_FD"))
 
-	executionHistoryService, err := NewExecutionHistoryService()
-	if err != nil {
-		log.Printf("Warning: failed to initialize execution history service: %v", err)
-		executionHistoryService = nil
-	} else {
-		log.Printf("Container: execution history service initialized")
-	}
+	executionHistoryService := NewExecutionHistoryService(db)
+	vaultService := NewVaultService()
 
 	return &Container{
 		ScriptService:    scriptService,
-		ExecutionService: NewExecutionService(),
+		ExecutionService: NewExecutionService(scriptService, vaultService),
 		TerminalService: NewTerminalService(TerminalServiceOptions{
 			targetCommandFile: os.Getenv("SCRIPTO_CMD_FD"),
 		}),
 		HistoryService:          NewHistoryService(),
 		ExecutionHistoryService: executionHistoryService,
+		VaultService:            vaultService,
+		AllowedValuesService:    NewAllowedValuesService(db),
 	}, nil
 }
@@ -0,0 +1,473 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+const (
+	IssueLegacyNotImported = "legacy_not_imported"
+	IssueMissingFile       = "missing_file"
+	IssueOrphanFile        = "orphan_file"
+	IssueMissingID         = "missing_id"
+	IssueDuplicateID       = "duplicate_id"
+	IssueDuplicateName     = "duplicate_name"
+	IssueStaleShortcut     = "stale_shortcut"
+	IssueMissingShortcut   = "missing_shortcut"
+	IssueOrphanHistory     = "orphan_history"
+)
+
+// DoctorIssue is one inconsistency found in the script store. Fix describes
+// the repair doctor would make, or is empty when the issue needs a manual
+// decision.
+type DoctorIssue struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	ScriptID string `json:"script_id,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Fix      string `json:"fix,omitempty"`
+	Fixed    bool   `json:"fixed"`
+	FixError string `json:"fix_error,omitempty"`
+
+	repair func() error
+}
+
+// RunDoctor checks the personal store, the visible project stores, shortcuts
+// and execution history. With fix set it repairs every issue that has a safe
+// repair: nothing is deleted, unreferenced files are moved aside and missing
+// bodies are only restored from recorded revisions.
+func (s *ScriptService) RunDoctor(fix bool) ([]*DoctorIssue, error) {
+	if fix {
+		unlock, err := s.lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+		if _, err := s.PurgeExpiredTrash(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	checks := []func() ([]*DoctorIssue, error){
+		s.checkLegacyImport,
+		s.checkProjectIDs,
+		s.checkMissingFiles,
+		s.checkOrphanFiles,
+		s.checkDuplicateNames,
+		s.checkShortcuts,
+		s.checkHistory,
+	}
+
+	var issues []*DoctorIssue
+	for _, check := range checks {
+		found, err := check()
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+
+	if !fix {
+		return issues, nil
+	}
+
+	for _, issue := range issues {
+		if issue.repair == nil {
+			continue
+		}
+		if err := issue.repair(); err != nil {
+			issue.FixError = err.Error()
+			continue
+		}
+		issue.Fixed = true
+	}
+	return issues, s.Reload()
+}
+
+func (s *ScriptService) checkLegacyImport() ([]*DoctorIssue, error) {
+	config, err := storage.ReadConfig(s.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.configPath, err)
+	}
+
+	pending := 0
+	seen := make(map[string]bool)
+	for scope, scripts := range config {
+		for _, script := range scripts {
+			script.Scope = scope
+			byID := script.ID != "" && !seen[script.ID]
+			exists, err := legacyScriptImported(s.db, script, byID)
+			if err != nil {
+				return nil, err
+			}
+			if script.ID != "" {
+				seen[script.ID] = true
+			}
+			if !exists {
+				pending++
+			}
+		}
+	}
+	if pending == 0 {
+		return nil, nil
+	}
+
+	return []*DoctorIssue{{
+		Kind:    IssueLegacyNotImported,
+		Message: fmt.Sprintf("%d scripts in %s are not in the database", pending, s.configPath),
+		Path:    s.configPath,
+		Fix:     "import them, assigning IDs to entries without one",
+		repair: func() error {
+			_, err := s.importLegacy()
+			return err
+		},
+	}}, nil
+}
+
+func (s *ScriptService) checkProjectIDs() ([]*DoctorIssue, error) {
+	personalIDs := make(map[string]bool)
+	for _, scripts := range s.config {
+		for _, script := range scripts {
+			if script.Source == "" {
+				personalIDs[script.ID] = true
+			}
+		}
+	}
+
+	var issues []*DoctorIssue
+	seen := make(map[string]string)
+	for _, project := range s.projects {
+		config, err := storage.ReadProjectConfig(project)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read project config: %w", err)
+		}
+
+		broken := 0
+		for _, script := range config.Scripts {
+			switch {
+			case script.ID == "":
+				issues = append(issues, &DoctorIssue{
+					Kind:    IssueMissingID,
+					Message: fmt.Sprintf("script '%s' in %s has no ID, so it gets a temporary one each run", script.Name, project.ConfigPath()),
+					Path:    project.ConfigPath(),
+					Fix:     "assign a new ID",
+				})
+				broken++
+			case personalIDs[script.ID] || seen[script.ID] != "":
+				other := seen[script.ID]
+				switch other {
+				case "":
+					other = "a personal script"
+				case project.ConfigPath():
+					other = "another entry in the same file"
+				}
+				issues = append(issues, &DoctorIssue{
+					Kind:     IssueDuplicateID,
+					Message:  fmt.Sprintf("script '%s' in %s shares its ID with %s", script.Name, project.ConfigPath(), other),
+					ScriptID: script.ID,
+					Path:     project.ConfigPath(),
+					Fix:      "assign a new ID to the project entry",
+				})
+				broken++
+			default:
+				seen[script.ID] = project.ConfigPath()
+			}
+		}
+
+		if broken > 0 {
+			source := project.ConfigPath()
+			repair := s.projectIDRepair(source, personalIDs, seen)
+			for _, issue := range issues[len(issues)-broken:] {
+				issue.repair = repair
+			}
+		}
+	}
+	return issues, nil
+}
+
+// projectIDRepair returns a repair that gives every ID-less or duplicated
+// entry of a project file a fresh ID. It runs once however many issues share
+// it.
+func (s *ScriptService) projectIDRepair(source string, personalIDs map[string]bool, seen map[string]string) func() error {
+	done := false
+	return func() error {
+		if done {
+			return nil
+		}
+		done = true
+		return s.updateProjectFile(source, func(config *storage.ProjectConfig) error {
+			local := make(map[string]bool)
+			for _, script := range config.Scripts {
+				owner := seen[script.ID]
+				if script.ID == "" || personalIDs[script.ID] || local[script.ID] || (owner != "" && owner != source) {
+					script.ID = uuid.New().String()
+				}
+				local[script.ID] = true
+			}
+			return nil
+		})
+	}
+}
+
+func (s *ScriptService) checkMissingFiles() ([]*DoctorIssue, error) {
+	var issues []*DoctorIssue
+	for _, scripts := range s.config {
+		for _, script := range scripts {
+			if script.FilePath != "" {
+				if _, err := os.Stat(script.FilePath); err == nil {
+					continue
+				} else if !os.IsNotExist(err) {
+					return nil, fmt.Errorf("failed to inspect %s: %w", script.FilePath, err)
+				}
+			}
+
+			issue := &DoctorIssue{
+				Kind:     IssueMissingFile,
+				Message:  fmt.Sprintf("script '%s' has no command file", script.Name),
+				ScriptID: script.ID,
+				Path:     script.FilePath,
+			}
+			if script.FilePath != "" {
+				issue.Message = fmt.Sprintf("command file of script '%s' does not exist", script.Name)
+			}
+
+			revisions, err := s.GetRevisions(script.ID)
+			if err != nil {
+				return nil, err
+			}
+			if len(revisions) > 0 {
+				latest := revisions[0]
+				issue.Fix = fmt.Sprintf("restore the body from revision %d", latest.Revision)
+				issue.repair = s.missingFileRepair(script, latest.Body)
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+func (s *ScriptService) missingFileRepair(script *entities.Script, body string) func() error {
+	return func() error {
+		if script.FilePath != "" {
+			return storage.WriteFileAtomic(script.FilePath, []byte(body), 0644)
+		}
+
+		filePath, err := s.saveScriptFile(script.Source, script.Name, body)
+		if err != nil {
+			return err
+		}
+		if script.Source != "" {
+			return s.updateProjectFile(script.Source, func(config *storage.ProjectConfig) error {
+				configScript := s.findProjectScript(config, script)
+				if configScript == nil {
+					return fmt.Errorf("script not found in project config")
+				}
+				configScript.FilePath = filePath
+				return nil
+			})
+		}
+		_, err = s.db.Exec("UPDATE scripts SET file_path = ?, version = version + 1 WHERE id = ?", filePath, script.ID)
+		return err
+	}
+}
+
+func (s *ScriptService) checkOrphanFiles() ([]*DoctorIssue, error) {
+	referenced := make(map[string]bool)
+	rows, err := s.db.Query("SELECT file_path FROM scripts")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		referenced[path] = true
+	}
+	rows.Close()
+	for _, scripts := range s.config {
+		for _, script := range scripts {
+			referenced[script.FilePath] = true
+		}
+	}
+	// Files of scripts.json entries that are not imported yet are not orphans.
+	if legacy, err := storage.ReadConfig(s.configPath); err == nil {
+		for _, scripts := range legacy {
+			for _, script := range scripts {
+				referenced[script.FilePath] = true
+			}
+		}
+	}
+
+	personalDir, err := storage.GetScriptsDir()
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{personalDir}
+	for _, project := range s.projects {
+		dirs = append(dirs, project.ScriptsDir())
+	}
+
+	orphanedDir := filepath.Join(filepath.Dir(s.configPath), "orphaned")
+	var issues []*DoctorIssue
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			// Dotfiles such as .gitkeep are not scripts, but leftovers of an
+			// interrupted atomic write are.
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") && !strings.Contains(entry.Name(), ".tmp-") {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if referenced[path] {
+				continue
+			}
+			target := filepath.Join(orphanedDir, entry.Name())
+			issues = append(issues, &DoctorIssue{
+				Kind:    IssueOrphanFile,
+				Message: fmt.Sprintf("%s is not used by any script", path),
+				Path:    path,
+				Fix:     fmt.Sprintf("move it to %s", target),
+				repair: func() error {
+					if err := os.MkdirAll(orphanedDir, 0755); err != nil {
+						return err
+					}
+					return os.Rename(path, target)
+				},
+			})
+		}
+	}
+	return issues, nil
+}
+
+func (s *ScriptService) checkDuplicateNames() ([]*DoctorIssue, error) {
+	scopes := make([]string, 0, len(s.config))
+	for scope := range s.config {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	var issues []*DoctorIssue
+	for _, scope := range scopes {
+		byName := make(map[string]int)
+		for _, script := range s.config[scope] {
+			for _, name := range ScriptNames(script) {
+				byName[script.Source+"\x00"+name]++
+			}
+		}
+		for key, count := range byName {
+			if count < 2 {
+				continue
+			}
+			_, name, _ := strings.Cut(key, "\x00")
+			issues = append(issues, &DoctorIssue{
+				Kind:    IssueDuplicateName,
+				Message: fmt.Sprintf("%d scripts are named or aliased '%s' in scope '%s'; rename or delete all but one", count, name, scope),
+			})
+		}
+	}
+	return issues, nil
+}
+
+func (s *ScriptService) checkShortcuts() ([]*DoctorIssue, error) {
+	binDir, err := storage.GetBinDir()
+	if err != nil {
+		return nil, err
+	}
+	config, err := s.loadPersonalScripts()
+	if err != nil {
+		return nil, err
+	}
+
+	shellExt := storage.GetShellExtension()
+	expected := make(map[string]string)
+	for _, script := range config["global"] {
+		for _, name := range ScriptNames(script) {
+			expected[storage.SanitizeForFilename(name)+shellExt] = name
+		}
+	}
+
+	sync := func() error { return storage.SyncShortcuts(config) }
+
+	var issues []*DoctorIssue
+	existing := make(map[string]bool)
+	entries, err := os.ReadDir(binDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", binDir, err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), shellExt) {
+			continue
+		}
+		existing[entry.Name()] = true
+		if _, ok := expected[entry.Name()]; ok {
+			continue
+		}
+		issues = append(issues, &DoctorIssue{
+			Kind:    IssueStaleShortcut,
+			Message: fmt.Sprintf("shortcut %s has no matching global script", entry.Name()),
+			Path:    filepath.Join(binDir, entry.Name()),
+			Fix:     "remove it",
+			repair:  sync,
+		})
+	}
+
+	for file, name := range expected {
+		if existing[file] {
+			continue
+		}
+		issues = append(issues, &DoctorIssue{
+			Kind:    IssueMissingShortcut,
+			Message: fmt.Sprintf("global script '%s' has no shortcut", name),
+			Path:    filepath.Join(binDir, file),
+			Fix:     "create it",
+			repair:  sync,
+		})
+	}
+	return issues, nil
+}
+
+func (s *ScriptService) checkHistory() ([]*DoctorIssue, error) {
+	var count int
+	err := s.db.QueryRow(
+		`SELECT COUNT(*) FROM execution_history
+		 WHERE script_id IS NOT NULL AND script_id NOT IN (SELECT id FROM scripts)`,
+	).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check execution history: %w", err)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	return []*DoctorIssue{{
+		Kind:    IssueOrphanHistory,
+		Message: fmt.Sprintf("%d execution history records point at scripts that no longer exist", count),
+		Fix:     "detach them from the missing scripts (the records are kept)",
+		repair: func() error {
+			_, err := s.db.Exec(
+				`UPDATE execution_history SET script_id = NULL
+				 WHERE script_id IS NOT NULL AND script_id NOT IN (SELECT id FROM scripts)`,
+			)
+			return err
+		},
+	}}, nil
+}
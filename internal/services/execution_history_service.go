@@ -10,7 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/vsuhanov/scripto/entities"
-	"github.com/vsuhanov/scripto/internal/storage"
+	"github.com/vsuhanov/scripto/internal/templatex"
 )
 
 type ScriptStats struct {
@@ -31,21 +31,17 @@ type ExecutionRecord struct {
 	OriginalScriptHash     string
 	ScriptName             string
 	ScriptScope            string
+	// Secrets are further values to redact before the record is stored, such
+	// as those read from the vault. They are not saved.
+	Secrets map[string]string
 }
 
 type ExecutionHistoryService struct {
 	db *sql.DB
 }
 
-func NewExecutionHistoryService() (*ExecutionHistoryService, error) {
-	dbPath, _ := storage.GetSQLitePath()
-	log.Printf("NewExecutionHistoryService: opening sqlite at %q", dbPath)
-	db, err := storage.OpenSQLite()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open sqlite: %w", err)
-	}
-	log.Printf("NewExecutionHistoryService: sqlite opened successfully")
-	return &ExecutionHistoryService{db: db}, nil
+func NewExecutionHistoryService(db *sql.DB) *ExecutionHistoryService {
+	return &ExecutionHistoryService{db: db}
 }
 
 func (s *ExecutionHistoryService) Close() {
@@ -60,13 +56,24 @@ func sha256hex(data string) string {
 }
 
 func (s *ExecutionHistoryService) SaveExecution(record ExecutionRecord) {
-	log.Printf("SaveExecution: scriptID=%q executedScript=%q", record.ScriptID, record.ExecutedScript)
+	log.Printf("SaveExecution: scriptID=%q", record.ScriptID)
 	if record.ID == "" {
 		record.ID = uuid.New().String()
 	}
 	if record.ExecutionTimestamp == 0 {
 		record.ExecutionTimestamp = time.Now().Unix()
 	}
+	secrets := secretValues(record.OriginalScript, record.PlaceholderValues)
+	for key, value := range record.Secrets {
+		if secrets == nil {
+			secrets = make(map[string]string)
+		}
+		secrets[key] = value
+	}
+	if len(secrets) > 0 {
+		record.ExecutedScript = templatex.Redact(record.ExecutedScript, secrets)
+		record.PlaceholderValues = templatex.RedactValues(record.PlaceholderValues, secrets)
+	}
 	record.ExecutedScriptHash = sha256hex(record.ExecutedScript)
 	record.OriginalScriptHash = sha256hex(record.OriginalScript)
 
@@ -81,7 +88,7 @@ func (s *ExecutionHistoryService) SaveExecution(record ExecutionRecord) {
 		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		record.ID,
 		record.ExecutionTimestamp,
-		record.ScriptID,
+		nullableID(record.ScriptID),
 		record.ExecutedScript,
 		record.OriginalScript,
 		string(pvJSON),
@@ -125,7 +132,7 @@ func (s *ExecutionHistoryService) GetExecutionCount(scriptID string) (int, error
 }
 
 func (s *ExecutionHistoryService) GetFrecencyScores() map[string]float64 {
-	rows, err := s.db.Query(`SELECT script_id, execution_timestamp FROM execution_history`)
+	rows, err := s.db.Query(`SELECT script_id, execution_timestamp FROM execution_history WHERE script_id IS NOT NULL`)
 	if err != nil {
 		return map[string]float64{}
 	}
@@ -148,7 +155,7 @@ func (s *ExecutionHistoryService) GetFrecencyScores() map[string]float64 {
 
 func (s *ExecutionHistoryService) GetAllScriptStats() (map[string]ScriptStats, error) {
 	rows, err := s.db.Query(
-		`SELECT script_id, MAX(execution_timestamp), COUNT(*) FROM execution_history GROUP BY script_id`,
+		`SELECT script_id, MAX(execution_timestamp), COUNT(*) FROM execution_history WHERE script_id IS NOT NULL GROUP BY script_id`,
 	)
 	if err != nil {
 		return nil, err
@@ -176,7 +183,7 @@ func (s *ExecutionHistoryService) GetHistory(filter string, limit, offset int) (
 	var err error
 	if filter != "" {
 		rows, err = s.db.Query(
-			`SELECT id, execution_timestamp, script_id, executed_script, original_script, placeholder_values, working_directory, script_object_definition, executed_script_hash, original_script_hash
+			`SELECT id, execution_timestamp, COALESCE(script_id, ''), executed_script, original_script, placeholder_values, working_directory, script_object_definition, executed_script_hash, original_script_hash
 			 FROM execution_history
 			 WHERE executed_script LIKE ? OR script_id = ?
 			 ORDER BY execution_timestamp DESC LIMIT ? OFFSET ?`,
@@ -184,7 +191,7 @@ func (s *ExecutionHistoryService) GetHistory(filter string, limit, offset int) (
 		)
 	} else {
 		rows, err = s.db.Query(
-			`SELECT id, execution_timestamp, script_id, executed_script, original_script, placeholder_values, working_directory, script_object_definition, executed_script_hash, original_script_hash
+			`SELECT id, execution_timestamp, COALESCE(script_id, ''), executed_script, original_script, placeholder_values, working_directory, script_object_definition, executed_script_hash, original_script_hash
 			 FROM execution_history
 			 ORDER BY execution_timestamp DESC LIMIT ? OFFSET ?`,
 			limit, offset,
@@ -199,7 +206,7 @@ func (s *ExecutionHistoryService) GetHistory(filter string, limit, offset int) (
 
 func (s *ExecutionHistoryService) GetScriptIDsRunFromDirectory(dir string) ([]string, error) {
 	rows, err := s.db.Query(
-		`SELECT DISTINCT script_id FROM execution_history WHERE working_directory = ?`,
+		`SELECT DISTINCT script_id FROM execution_history WHERE working_directory = ? AND script_id IS NOT NULL`,
 		dir,
 	)
 	if err != nil {
@@ -219,7 +226,7 @@ func (s *ExecutionHistoryService) GetScriptIDsRunFromDirectory(dir string) ([]st
 
 func (s *ExecutionHistoryService) GetScriptHistory(scriptID string, limit int) ([]ExecutionRecord, error) {
 	rows, err := s.db.Query(
-		`SELECT id, execution_timestamp, script_id, executed_script, original_script, placeholder_values, working_directory, script_object_definition, executed_script_hash, original_script_hash
+		`SELECT id, execution_timestamp, COALESCE(script_id, ''), executed_script, original_script, placeholder_values, working_directory, script_object_definition, executed_script_hash, original_script_hash
 		 FROM execution_history
 		 WHERE script_id = ?
 		 ORDER BY execution_timestamp DESC LIMIT ?`,
@@ -232,6 +239,13 @@ func (s *ExecutionHistoryService) GetScriptHistory(scriptID string, limit int) (
 	return scanExecutionRecords(rows)
 }
 
+func nullableID(id string) any {
+	if id == "" {
+		return nil
+	}
+	return id
+}
+
 func scanExecutionRecords(rows *sql.Rows) ([]ExecutionRecord, error) {
 	var records []ExecutionRecord
 	for rows.Next() {
@@ -3,6 +3,8 @@ package services
 import (
 	"fmt"
 	"os"
+	"os/user"
+	"strconv"
 	"github.com/vsuhanov/scripto/entities"
 	"github.com/vsuhanov/scripto/internal/templatex"
 	"strings"
@@ -16,13 +18,128 @@ type ArgumentProcessingResult struct {
 	ParsedValues         map[string]string
 }
 
-type ExecutionService struct{}
+type ExecutionService struct {
+	scripts *ScriptService
+	vault   *VaultService
+	// resolved holds every value read from the vault, so it can be redacted
+	// wherever the command is shown or stored.
+	resolved map[string]string
+	// redacted maps every rendered command to the same command with its
+	// secrets redacted where they were rendered; see RedactCommand.
+	redacted map[string]string
+}
 
-func NewExecutionService() *ExecutionService {
-	return &ExecutionService{}
+func NewExecutionService(scripts *ScriptService, vault *VaultService) *ExecutionService {
+	return &ExecutionService{scripts: scripts, vault: vault, resolved: make(map[string]string), redacted: make(map[string]string)}
 }
 
-func (es *ExecutionService) ProcessScriptArguments(s *entities.Script, scriptArgs []string) (*ArgumentProcessingResult, error) {
+// applyScopeVariables makes the variables of the scopes that apply in the
+// current directory the defaults of placeholders with the same name, ahead of
+// their defaultValue annotations. Secret placeholders are left alone.
+func (es *ExecutionService) applyScopeVariables(metas []templatex.VariableMeta) {
+	if es.scripts == nil {
+		return
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	variables := es.scripts.ResolveScopeVariables(cwd)
+	for i := range metas {
+		if value := variables[metas[i].Name]; value != "" && !metas[i].Secret {
+			metas[i].DefaultValue = value
+		}
+	}
+}
+
+// AutoQuote reports whether the placeholder values of script are shell-quoted
+// when it renders: when the script asks for it or SCRIPTO_AUTO_QUOTE is set.
+func AutoQuote(s *entities.Script) bool {
+	if s != nil && s.AutoQuote {
+		return true
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv("SCRIPTO_AUTO_QUOTE"))
+	return enabled
+}
+
+// TemplateContext returns what {{ .Scripto.* }} reads when script runs from
+// the current directory. Values that cannot be found are left empty.
+func TemplateContext(s *entities.Script) templatex.Context {
+	ctx := templatex.Context{}
+	if cwd, err := os.Getwd(); err == nil {
+		ctx.Cwd = cwd
+		ctx.GitRoot, ctx.GitBranch = GitRootAndBranch(cwd)
+	}
+	ctx.Hostname, _ = os.Hostname()
+	if u, err := user.Current(); err == nil {
+		ctx.User = u.Username
+	} else {
+		ctx.User = os.Getenv("USER")
+	}
+	if s != nil {
+		ctx.ScriptName = s.Name
+		ctx.Scope = s.Scope
+		if s.OriginalScope != "" {
+			ctx.Scope = s.OriginalScope
+		}
+	}
+	return ctx
+}
+
+// render executes the script template, reading vault secrets for the current
+// directory.
+func (es *ExecutionService) render(s *entities.Script, template string, values map[string]string) (string, error) {
+	opts := templatex.Options{AutoQuote: AutoQuote(s), Context: TemplateContext(s)}
+	command, err := templatex.Render(template, values, es.withVault(opts))
+	if err != nil {
+		return "", err
+	}
+	if len(secretValues(template, values)) == 0 && !templatex.UsesVaultSecrets(template) {
+		return command, nil
+	}
+	if redacted, err := templatex.RenderRedacted(template, values, opts); err == nil {
+		es.redacted[command] = redacted
+	}
+	return command, nil
+}
+
+// RedactCommand returns command, which may wrap a command rendered by this
+// service, with the secrets of that rendering redacted where the template
+// printed them.
+func (es *ExecutionService) RedactCommand(command string) string {
+	for rendered, redacted := range es.redacted {
+		command = strings.Replace(command, rendered, redacted, 1)
+	}
+	return command
+}
+
+// withVault makes opts read {{ secret "NAME" }} from the vault, remembering
+// the values it reads.
+func (es *ExecutionService) withVault(opts templatex.Options) templatex.Options {
+	opts.Lookup = func(name string) (string, error) {
+		if es.vault == nil {
+			return "", ErrVaultLocked
+		}
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		value, err := es.vault.Resolve(name, cwd)
+		if err != nil {
+			return "", err
+		}
+		if value != "" {
+			es.resolved["vault:"+name] = value
+		}
+		return value, nil
+	}
+	return opts
+}
+
+// ProcessScriptArguments fills the placeholders of the script from scriptArgs
+// and renders it when nothing is missing. workingDir is where the command
+// runs, which relative path values are checked against.
+func (es *ExecutionService) ProcessScriptArguments(s *entities.Script, scriptArgs []string, workingDir string) (*ArgumentProcessingResult, error) {
 	if s.FilePath == "" {
 		return nil, fmt.Errorf("script has no file path or command content")
 	}
@@ -55,16 +172,33 @@ func (es *ExecutionService) ProcessScriptArguments(s *entities.Script, scriptArg
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
+	es.applyScopeVariables(metas)
 
 	if len(metas) == 0 {
+		// Rendered even without placeholders for vault secrets, helper
+		// functions and .Scripto fields.
+		finalCommand, err := es.render(s, trimmed, map[string]string{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+		// Without placeholders the arguments are passed on to the command.
+		for _, arg := range scriptArgs {
+			finalCommand += " " + templatex.ShellQuote(arg)
+		}
 		return &ArgumentProcessingResult{
 			NeedsPlaceholderForm: false,
-			FinalCommand:         trimmed,
+			FinalCommand:         finalCommand,
 			OriginalScript:       contentStr,
 		}, nil
 	}
 
-	parsedValues := parseNamedArgs(scriptArgs)
+	parsedValues, positional := parseArgs(scriptArgs)
+	if err := templatex.AssignPositional(metas, parsedValues, positional); err != nil {
+		return nil, err
+	}
+	if err := validateValues(metas, parsedValues, workingDir); err != nil {
+		return nil, err
+	}
 
 	allSatisfied := true
 	for _, meta := range metas {
@@ -84,7 +218,7 @@ func (es *ExecutionService) ProcessScriptArguments(s *entities.Script, scriptArg
 		for name, val := range parsedValues {
 			values[name] = val
 		}
-		finalCommand, err := templatex.Execute(trimmed, values)
+		finalCommand, err := es.render(s, trimmed, values)
 		if err != nil {
 			return nil, fmt.Errorf("failed to render template: %w", err)
 		}
@@ -97,6 +231,12 @@ func (es *ExecutionService) ProcessScriptArguments(s *entities.Script, scriptArg
 		}, nil
 	}
 
+	// Values already given start out filled in on the form.
+	for i := range metas {
+		if value := parsedValues[metas[i].Name]; value != "" {
+			metas[i].DefaultValue = value
+		}
+	}
 	return &ArgumentProcessingResult{
 		NeedsPlaceholderForm: true,
 		Metas:                metas,
@@ -105,10 +245,14 @@ func (es *ExecutionService) ProcessScriptArguments(s *entities.Script, scriptArg
 	}, nil
 }
 
-func parseNamedArgs(args []string) map[string]string {
-	result := make(map[string]string)
+// parseArgs splits script arguments into --NAME=VALUE pairs and positional
+// arguments. A --flag without a value is ignored.
+func parseArgs(args []string) (map[string]string, []string) {
+	named := make(map[string]string)
+	var positional []string
 	for _, arg := range args {
 		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
 			continue
 		}
 		trimmed := strings.TrimPrefix(arg, "--")
@@ -116,12 +260,12 @@ func parseNamedArgs(args []string) map[string]string {
 		if idx < 0 {
 			continue
 		}
-		result[trimmed[:idx]] = trimmed[idx+1:]
+		named[trimmed[:idx]] = trimmed[idx+1:]
 	}
-	return result
+	return named, positional
 }
 
-func (es *ExecutionService) PrepareExecution(s *entities.Script, _ []string, placeholderValues map[string]string) (string, error) {
+func (es *ExecutionService) PrepareExecution(s *entities.Script, _ []string, placeholderValues map[string]string, workingDir string) (string, error) {
 	content, err := os.ReadFile(s.FilePath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read script file %s: %w", s.FilePath, err)
@@ -133,6 +277,7 @@ func (es *ExecutionService) PrepareExecution(s *entities.Script, _ []string, pla
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
+	es.applyScopeVariables(metas)
 
 	values := make(map[string]string)
 	for _, meta := range metas {
@@ -145,8 +290,127 @@ func (es *ExecutionService) PrepareExecution(s *entities.Script, _ []string, pla
 			values[name] = value
 		}
 	}
+	if err := validateValues(metas, values, workingDir); err != nil {
+		return "", err
+	}
+
+	return es.render(s, contentStr, values)
+}
 
-	return templatex.Execute(contentStr, values)
+// Preview renders the script with values, defaults and scope variables as it
+// would run, without reading the vault, and returns the placeholders still
+// missing a value. Secret values are redacted.
+func (es *ExecutionService) Preview(s *entities.Script, values map[string]string) (string, []string, error) {
+	content, err := os.ReadFile(s.FilePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read script file %s: %w", s.FilePath, err)
+	}
+	if strings.HasPrefix(string(content), "#!") {
+		return s.FilePath, nil, nil
+	}
+
+	contentStr := strings.TrimSpace(string(content))
+	metas, err := templatex.ExtractVariables(contentStr)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	es.applyScopeVariables(metas)
+
+	merged := make(map[string]string)
+	var missing []string
+	for _, meta := range metas {
+		value := values[meta.Name]
+		if value == "" {
+			value = meta.DefaultValue
+		}
+		if value == "" {
+			missing = append(missing, meta.Name)
+			continue
+		}
+		merged[meta.Name] = value
+	}
+	if err := validateValues(metas, merged, ""); err != nil {
+		return "", nil, err
+	}
+
+	command, err := templatex.RenderRedacted(contentStr, merged, templatex.Options{AutoQuote: AutoQuote(s), Context: TemplateContext(s)})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to render template: %w", err)
+	}
+	return command, missing, nil
+}
+
+// InvalidValueError is returned when a placeholder value does not pass its
+// type or pattern annotation.
+type InvalidValueError struct {
+	Name string
+	Err  error
+}
+
+func (e *InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value for --%s: %v", e.Name, e.Err)
+}
+
+func (e *InvalidValueError) Unwrap() error {
+	return e.Err
+}
+
+// validateValues checks the given placeholder values against their type and
+// pattern annotations, with relative paths taken from dir.
+func validateValues(metas []templatex.VariableMeta, values map[string]string, dir string) error {
+	for _, meta := range metas {
+		if err := templatex.ValidateValue(meta, values[meta.Name], dir); err != nil {
+			return &InvalidValueError{Name: meta.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// SecretValues returns the values given for the secret placeholders of the
+// script template, keyed by placeholder name, along with the values read from
+// the vault, keyed by "vault:NAME".
+func (es *ExecutionService) SecretValues(template string, values map[string]string) map[string]string {
+	secrets := secretValues(template, values)
+	if len(es.resolved) == 0 {
+		return secrets
+	}
+	if secrets == nil {
+		secrets = make(map[string]string)
+	}
+	for key, value := range es.resolved {
+		secrets[key] = value
+	}
+	return secrets
+}
+
+func secretValues(template string, values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	metas, err := templatex.ExtractVariables(strings.TrimSpace(template))
+	if err != nil {
+		return nil
+	}
+	return templatex.SecretValues(metas, values)
+}
+
+// HasSecretPlaceholders reports whether the script template asks for a secret
+// value or reads one from the vault, which is then missing from its stored
+// executions.
+func HasSecretPlaceholders(template string) bool {
+	if templatex.UsesVaultSecrets(strings.TrimSpace(template)) {
+		return true
+	}
+	metas, err := templatex.ExtractVariables(strings.TrimSpace(template))
+	if err != nil {
+		return false
+	}
+	for _, meta := range metas {
+		if meta.Secret {
+			return true
+		}
+	}
+	return false
 }
 
 func (es *ExecutionService) PrepareDirectExecution(processingResult *ArgumentProcessingResult) (string, error) {
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const gitScopePrefix = "git:"
+
+type gitRepo struct {
+	scope string
+	root  string
+}
+
+// gitRepoCache holds the repositories found since the scripts were last
+// loaded; Reload clears it so remotes added in the meantime are seen.
+var (
+	gitRepoCache   = make(map[string]gitRepo)
+	gitRepoCacheMu sync.Mutex
+)
+
+func clearGitRepoCache() {
+	gitRepoCacheMu.Lock()
+	defer gitRepoCacheMu.Unlock()
+	clear(gitRepoCache)
+}
+
+// IsGitScope reports whether scope names a git repository by its remote, such
+// as "git:github.com/org/repo", so it follows the repository wherever it is
+// cloned.
+func IsGitScope(scope string) bool {
+	return strings.HasPrefix(scope, gitScopePrefix) && len(scope) > len(gitScopePrefix)
+}
+
+// GitScopeForDir returns the git scope of the repository containing dir and
+// the repository's root directory. The scope is taken from the "origin"
+// remote, or the first remote when there is no origin.
+func GitScopeForDir(dir string) (scope, root string, ok bool) {
+	gitRepoCacheMu.Lock()
+	defer gitRepoCacheMu.Unlock()
+
+	if repo, cached := gitRepoCache[dir]; cached {
+		return repo.scope, repo.root, repo.scope != ""
+	}
+	repo := findGitRepo(dir)
+	if repo.scope != "" {
+		// A directory outside a repository, or one without a remote yet, is
+		// looked up again next time.
+		gitRepoCache[dir] = repo
+	}
+	return repo.scope, repo.root, repo.scope != ""
+}
+
+func findGitRepo(dir string) gitRepo {
+	for current := dir; ; {
+		gitPath := filepath.Join(current, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			configPath := gitConfigPath(gitPath, info.IsDir())
+			if remote := readGitRemote(configPath); remote != "" {
+				return gitRepo{scope: gitScopePrefix + normalizeGitRemote(remote), root: current}
+			}
+			return gitRepo{}
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return gitRepo{}
+		}
+		current = parent
+	}
+}
+
+// GitRootAndBranch returns the root of the git repository containing dir,
+// whether or not it has a remote, and its checked-out branch. The branch is
+// empty when HEAD is detached, and both are empty outside a repository.
+func GitRootAndBranch(dir string) (root, branch string) {
+	for current := dir; ; {
+		gitPath := filepath.Join(current, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			if gitDir := resolveGitDir(gitPath, info.IsDir()); gitDir != "" {
+				if data, err := os.ReadFile(filepath.Join(gitDir, "HEAD")); err == nil {
+					if ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: refs/heads/"); ok {
+						branch = ref
+					}
+				}
+			}
+			return current, branch
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", ""
+		}
+		current = parent
+	}
+}
+
+// resolveGitDir returns the git directory of a worktree. In worktrees and
+// submodules .git is a file pointing at the real git directory.
+func resolveGitDir(gitPath string, isDir bool) string {
+	if isDir {
+		return gitPath
+	}
+	data, err := os.ReadFile(gitPath)
+	if err != nil {
+		return ""
+	}
+	gitDir := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(gitPath), gitDir)
+	}
+	return gitDir
+}
+
+// gitConfigPath finds the config of a repository. Worktrees share the config
+// of the main repository.
+func gitConfigPath(gitPath string, isDir bool) string {
+	gitDir := resolveGitDir(gitPath, isDir)
+	if gitDir == "" {
+		return ""
+	}
+	if data, err := os.ReadFile(filepath.Join(gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(gitDir, common)
+		}
+		gitDir = common
+	}
+	return filepath.Join(gitDir, "config")
+}
+
+// readGitRemote returns the URL of the origin remote, or of the first remote
+// when there is no origin.
+func readGitRemote(configPath string) string {
+	file, err := os.Open(configPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var first, origin, section string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "url" || !strings.HasPrefix(section, "remote ") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if first == "" {
+			first = value
+		}
+		if strings.Trim(strings.TrimPrefix(section, "remote "), `" `) == "origin" && origin == "" {
+			origin = value
+		}
+	}
+	if origin != "" {
+		return origin
+	}
+	return first
+}
+
+// normalizeGitRemote turns the different spellings of a remote into
+// host/path, so that https://github.com/org/repo.git and
+// git@github.com:org/repo map to the same scope.
+func normalizeGitRemote(remote string) string {
+	url := remote
+	if scheme, rest, ok := strings.Cut(url, "://"); ok && !strings.Contains(scheme, "/") {
+		url = rest
+	} else if !strings.HasPrefix(url, "/") && !strings.HasPrefix(url, ".") {
+		// scp-like syntax: [user@]host:path
+		if host, path, ok := strings.Cut(url, ":"); ok && !strings.Contains(host, "/") {
+			url = host + "/" + path
+		}
+	}
+
+	host, path, hasPath := strings.Cut(url, "/")
+	if hasPath && host != "" {
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		if colon := strings.Index(host, ":"); colon >= 0 {
+			host = host[:colon]
+		}
+		url = strings.ToLower(host) + "/" + path
+	}
+	url = strings.TrimSuffix(strings.TrimRight(url, "/"), ".git")
+	return strings.TrimRight(url, "/")
+}
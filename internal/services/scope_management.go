@@ -0,0 +1,350 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/vsuhanov/scripto/entities"
+)
+
+// ScopeInfo describes a scope that holds personal scripts or variables.
+type ScopeInfo struct {
+	Scope    string `json:"scope"`
+	Kind     string `json:"kind"`
+	Scripts  int    `json:"scripts"`
+	Archived int    `json:"archived"`
+	// Exists reports whether the directory of a directory or pattern scope is
+	// still there; scopes that name no directory always exist.
+	Exists bool `json:"exists"`
+}
+
+// ScopeKind names the kind of scope: global, directory, pattern, git or marker.
+func ScopeKind(scope string) string {
+	switch {
+	case scope == "global":
+		return "global"
+	case IsGitScope(scope):
+		return "git"
+	case IsMarkerScope(scope):
+		return "marker"
+	case IsPatternScope(scope):
+		return "pattern"
+	default:
+		return "directory"
+	}
+}
+
+func scopeDirExists(scope string) bool {
+	dir := scope
+	switch ScopeKind(scope) {
+	case "directory":
+	case "pattern":
+		includes, _ := scopePatterns(scope)
+		if len(includes) == 0 {
+			return false
+		}
+		dir, _ = doublestar.SplitPattern(includes[0])
+	default:
+		return true
+	}
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// ListScopes returns every scope with personal scripts or variables, sorted,
+// with the number of scripts in it. Trashed scripts are not counted.
+func (s *ScriptService) ListScopes() ([]ScopeInfo, error) {
+	rows, err := s.db.Query(
+		`SELECT sc.path,
+		        COUNT(s.id),
+		        COALESCE(SUM(s.archived), 0),
+		        sc.variables != '{}'
+		 FROM scopes sc
+		 LEFT JOIN scripts s ON s.scope_id = sc.id AND s.source = '' AND s.deleted_at IS NULL
+		 GROUP BY sc.id
+		 ORDER BY sc.path`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scopes: %w", err)
+	}
+	defer rows.Close()
+
+	var scopes []ScopeInfo
+	for rows.Next() {
+		var info ScopeInfo
+		var hasVariables bool
+		if err := rows.Scan(&info.Scope, &info.Scripts, &info.Archived, &hasVariables); err != nil {
+			return nil, fmt.Errorf("failed to list scopes: %w", err)
+		}
+		if info.Scripts == 0 && !hasVariables {
+			continue
+		}
+		info.Kind = ScopeKind(info.Scope)
+		info.Exists = scopeDirExists(info.Scope)
+		scopes = append(scopes, info)
+	}
+	return scopes, rows.Err()
+}
+
+// MoveScope moves the personal scripts and variables of from to to, which
+// must not hold scripts yet, and returns the number of scripts moved. See
+// MergeScope.
+func (s *ScriptService) MoveScope(from, to string) (int, error) {
+	return s.relocateScope(from, to, false)
+}
+
+// MergeScope moves the personal scripts and variables of from into to, which
+// may already hold scripts. A script name used in both scopes is an error, and
+// variables already set in to are kept.
+//
+// When from is a directory, the scopes below it and the working directories
+// recorded in execution history move along, so scripts that were run there
+// are still found from the new location.
+func (s *ScriptService) MergeScope(from, to string) (int, error) {
+	return s.relocateScope(from, to, true)
+}
+
+func (s *ScriptService) relocateScope(from, to string, merge bool) (int, error) {
+	if err := validateScope(from); err != nil {
+		return 0, err
+	}
+	if err := validateScope(to); err != nil {
+		return 0, err
+	}
+	if from == to {
+		return 0, fmt.Errorf("source and destination scope are the same")
+	}
+	if ScopeKind(from) == "directory" && strings.HasPrefix(to, from+string(filepath.Separator)) {
+		return 0, fmt.Errorf("cannot move scope '%s' into its own subdirectory", from)
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	mapping := map[string]string{from: to}
+	moves, err := scopeMoves(tx, from, mapping)
+	if err != nil {
+		return 0, err
+	}
+	if len(moves) == 0 {
+		return 0, fmt.Errorf("scope '%s' not found", from)
+	}
+
+	moved := 0
+	for _, move := range moves {
+		count, err := s.relocateScopeRow(tx, move.from, move.to, merge)
+		if err != nil {
+			return 0, err
+		}
+		moved += count
+	}
+	if err := relocateHistory(tx, mapping); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit scope move: %w", err)
+	}
+	if err := s.Reload(); err != nil {
+		return 0, err
+	}
+	if from == "global" || to == "global" {
+		return moved, s.SyncShortcuts()
+	}
+	return moved, nil
+}
+
+type scopeMove struct {
+	from string
+	to   string
+}
+
+// scopeMoves returns from and, for a directory, every scope below it, paired
+// with where each one moves to.
+func scopeMoves(tx *sql.Tx, from string, mapping map[string]string) ([]scopeMove, error) {
+	rows, err := tx.Query("SELECT path FROM scopes ORDER BY path")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scopes: %w", err)
+	}
+	defer rows.Close()
+
+	var moves []scopeMove
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to read scopes: %w", err)
+		}
+		if path != from && (ScopeKind(from) != "directory" || !strings.HasPrefix(path, from+string(filepath.Separator))) {
+			continue
+		}
+		moves = append(moves, scopeMove{from: path, to: MapScope(path, mapping)})
+	}
+	return moves, rows.Err()
+}
+
+// relocateScopeRow moves the personal scripts of one scope, trashed ones
+// included, and returns how many live scripts moved.
+func (s *ScriptService) relocateScopeRow(tx *sql.Tx, from, to string, merge bool) (int, error) {
+	var fromID int64
+	var fromVariables string
+	if err := tx.QueryRow("SELECT id, variables FROM scopes WHERE path = ?", from).Scan(&fromID, &fromVariables); err != nil {
+		return 0, fmt.Errorf("failed to read scope '%s': %w", from, err)
+	}
+	toID, err := ensureScope(tx, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save scope: %w", err)
+	}
+
+	moving, err := scopeRowScripts(tx, fromID)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := scopeRowScripts(tx, toID)
+	if err != nil {
+		return 0, err
+	}
+	if len(existing) > 0 && len(moving) > 0 && !merge {
+		return 0, fmt.Errorf("scope '%s' already has scripts; use merge to combine the scopes", to)
+	}
+	for _, script := range moving {
+		script.Scope = to
+		if err := s.checkForDuplicateName(existing, script, nil); err != nil {
+			return 0, fmt.Errorf("cannot move '%s': %w", from, err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE scripts SET scope_id = ?, version = version + 1 WHERE scope_id = ? AND source = ''",
+		toID, fromID,
+	); err != nil {
+		return 0, fmt.Errorf("failed to move scripts: %w", err)
+	}
+
+	var toVariables string
+	if err := tx.QueryRow("SELECT variables FROM scopes WHERE id = ?", toID).Scan(&toVariables); err != nil {
+		return 0, fmt.Errorf("failed to read scope variables: %w", err)
+	}
+	variables := decodeScopeVariables(fromVariables)
+	for name, value := range decodeScopeVariables(toVariables) {
+		variables[name] = value
+	}
+	encoded, err := json.Marshal(variables)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode scope variables: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE scopes SET variables = ? WHERE id = ?", string(encoded), toID); err != nil {
+		return 0, fmt.Errorf("failed to save scope variables: %w", err)
+	}
+
+	if _, err := tx.Exec(
+		"DELETE FROM scopes WHERE id = ? AND NOT EXISTS (SELECT 1 FROM scripts WHERE scope_id = ?)",
+		fromID, fromID,
+	); err != nil {
+		return 0, fmt.Errorf("failed to remove scope '%s': %w", from, err)
+	}
+	if _, err := tx.Exec("UPDATE scopes SET variables = '{}' WHERE id = ?", fromID); err != nil {
+		return 0, fmt.Errorf("failed to clear scope variables: %w", err)
+	}
+	return len(moving), nil
+}
+
+// scopeRowScripts returns the personal scripts of a scope that are not in the
+// trash.
+func scopeRowScripts(tx *sql.Tx, scopeID int64) ([]*entities.Script, error) {
+	rows, err := tx.Query(
+		`SELECT s.id, s.name, s.description, s.file_path, s.archived, s.version, sc.path, s.aliases, s.auto_quote
+		 FROM scripts s JOIN scopes sc ON sc.id = s.scope_id
+		 WHERE s.source = '' AND s.deleted_at IS NULL AND s.scope_id = ?`,
+		scopeID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scripts: %w", err)
+	}
+	return scanScripts(rows)
+}
+
+// relocateHistory rewrites the working directories and script scopes recorded
+// in execution history, which is how scripts run from a directory are found
+// there again. Working directories only follow a directory scope moved to
+// another directory.
+func relocateHistory(tx *sql.Tx, mapping map[string]string) error {
+	type historyUpdate struct {
+		id, workingDir, definition string
+	}
+
+	dirMapping := make(map[string]string)
+	for from, to := range mapping {
+		if ScopeKind(from) == "directory" && ScopeKind(to) == "directory" {
+			dirMapping[from] = to
+		}
+	}
+
+	rows, err := tx.Query("SELECT id, working_directory, script_object_definition FROM execution_history")
+	if err != nil {
+		return fmt.Errorf("failed to read execution history: %w", err)
+	}
+	var updates []historyUpdate
+	for rows.Next() {
+		var id, workingDir, definition string
+		if err := rows.Scan(&id, &workingDir, &definition); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read execution history: %w", err)
+		}
+		newWorkingDir := MapScope(workingDir, dirMapping)
+		newDefinition := relocateScriptDefinition(definition, mapping)
+		if newWorkingDir != workingDir || newDefinition != definition {
+			updates = append(updates, historyUpdate{id: id, workingDir: newWorkingDir, definition: newDefinition})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read execution history: %w", err)
+	}
+
+	for _, update := range updates {
+		if _, err := tx.Exec(
+			"UPDATE execution_history SET working_directory = ?, script_object_definition = ? WHERE id = ?",
+			update.workingDir, update.definition, update.id,
+		); err != nil {
+			return fmt.Errorf("failed to update execution history: %w", err)
+		}
+	}
+	return nil
+}
+
+// relocateScriptDefinition rewrites the scope of a script stored as JSON in a
+// history record, leaving definitions it cannot read untouched.
+func relocateScriptDefinition(definition string, mapping map[string]string) string {
+	var script map[string]any
+	if err := json.Unmarshal([]byte(definition), &script); err != nil {
+		return definition
+	}
+	scope, ok := script["scope"].(string)
+	if !ok {
+		return definition
+	}
+	mapped := MapScope(scope, mapping)
+	if mapped == scope {
+		return definition
+	}
+	script["scope"] = mapped
+	encoded, err := json.Marshal(script)
+	if err != nil {
+		return definition
+	}
+	return string(encoded)
+}
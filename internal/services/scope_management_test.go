@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/vsuhanov/scripto/entities"
+)
+
+func TestMoveScope_RelocatesHistory(t *testing.T) {
+	tests := []struct {
+		name           string
+		to             string
+		wantWorkingDir string
+		wantDefinition string
+	}{
+		{"to a directory", "/srv/web", "/srv/web/sub", `{"scope":"/srv/web"}`},
+		{"to global", "global", "/srv/app/sub", `{"scope":"global"}`},
+		{"to a pattern", "/srv/*", "/srv/app/sub", `{"scope":"/srv/*"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestScriptService(t, "")
+			if err := s.SaveScript(&entities.Script{Name: "deploy", Scope: "/srv/app"}, "make deploy", nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.db.Exec(
+				`INSERT INTO execution_history (id, execution_timestamp, executed_script, original_script, placeholder_values, working_directory, script_object_definition, executed_script_hash, original_script_hash)
+				 VALUES ('h1', 1, 'make deploy', 'make deploy', '{}', '/srv/app/sub', '{"scope":"/srv/app"}', '', '')`,
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.MoveScope("/srv/app", tt.to); err != nil {
+				t.Fatal(err)
+			}
+
+			var workingDir, definition string
+			if err := s.db.QueryRow("SELECT working_directory, script_object_definition FROM execution_history WHERE id = 'h1'").Scan(&workingDir, &definition); err != nil {
+				t.Fatal(err)
+			}
+			if workingDir != tt.wantWorkingDir {
+				t.Errorf("expected working directory %q, got %q", tt.wantWorkingDir, workingDir)
+			}
+			if definition != tt.wantDefinition {
+				t.Errorf("expected definition %s, got %s", tt.wantDefinition, definition)
+			}
+		})
+	}
+}
@@ -1,22 +1,208 @@
 package services
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/bmatcuk/doublestar/v4"
 )
 
+const markerScopePrefix = "marker:"
+
+// markerRootCache holds the marker roots found since the scripts were last
+// loaded; Reload clears it so marker files created in the meantime are seen.
+var (
+	markerRootCache   = make(map[string]string)
+	markerRootCacheMu sync.Mutex
+)
+
+func clearMarkerRootCache() {
+	markerRootCacheMu.Lock()
+	defer markerRootCacheMu.Unlock()
+	clear(markerRootCache)
+}
+
+// IsPatternScope reports whether scope is a glob pattern, or a comma
+// separated list of patterns where those starting with '!' exclude
+// directories, e.g. "~/work/**,!~/work/legacy/**". A comma alone does not
+// make a pattern, so a directory like "/data/a,b" stays a directory.
 func IsPatternScope(scope string) bool {
-	return scope != "global" && strings.ContainsAny(scope, "*?[")
+	if scope == "global" || IsGitScope(scope) || IsMarkerScope(scope) {
+		return false
+	}
+	if strings.ContainsAny(scope, "*?[") {
+		return true
+	}
+	if !strings.Contains(scope, ",") {
+		return false
+	}
+	if strings.Contains(scope, "{") {
+		// {a,b} alternatives
+		return true
+	}
+	for _, part := range strings.Split(scope, ",") {
+		if strings.HasPrefix(strings.TrimSpace(part), "!") {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandScope expands a leading ~ in a directory scope to the home directory.
+// Patterns keep their ~, which is expanded when they are matched, so they
+// work on machines with different home directories.
+func ExpandScope(scope string) string {
+	if scope == "global" || IsMatchedScope(scope) {
+		return scope
+	}
+	return expandHome(scope)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// scopePatterns splits a pattern scope into its include and exclude patterns,
+// with ~ expanded. Commas inside {a,b} alternatives and [...] classes do not
+// separate patterns.
+func scopePatterns(scope string) (includes, excludes []string) {
+	var parts []string
+	depth, start := 0, 0
+	for i, r := range scope {
+		switch r {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, scope[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, scope[start:])
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if exclude, ok := strings.CutPrefix(part, "!"); ok {
+			excludes = append(excludes, expandHome(strings.TrimSpace(exclude)))
+		} else if part != "" {
+			includes = append(includes, expandHome(part))
+		}
+	}
+	return includes, excludes
+}
+
+func matchesAnyPattern(patterns []string, dir string) bool {
+	for _, pattern := range patterns {
+		if matched, err := doublestar.Match(pattern, dir); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMarkerScope reports whether scope applies to every project of a kind,
+// such as "marker:go.mod" for any directory inside a Go module.
+func IsMarkerScope(scope string) bool {
+	return strings.HasPrefix(scope, markerScopePrefix) && len(scope) > len(markerScopePrefix)
+}
+
+// MarkerScopeFile returns the file name a marker scope looks for.
+func MarkerScopeFile(scope string) string {
+	return strings.TrimPrefix(scope, markerScopePrefix)
+}
+
+// IsMatchedScope reports whether scope applies to the directories it matches,
+// like a glob pattern, a git repository or a marker file, rather than naming
+// one directory.
+func IsMatchedScope(scope string) bool {
+	return IsPatternScope(scope) || IsGitScope(scope) || IsMarkerScope(scope)
+}
+
+// MarkerRootForDir returns the nearest directory at or above dir that contains
+// the marker file of scope.
+func MarkerRootForDir(scope, dir string) (string, bool) {
+	marker := MarkerScopeFile(scope)
+	key := marker + "\x00" + dir
+
+	markerRootCacheMu.Lock()
+	defer markerRootCacheMu.Unlock()
+
+	if root, cached := markerRootCache[key]; cached {
+		return root, root != ""
+	}
+	root := ""
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, marker)); err == nil {
+			root = current
+			break
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+	if root != "" {
+		// A missing marker is looked up again next time.
+		markerRootCache[key] = root
+	}
+	return root, root != ""
 }
 
 func ScopeMatchesDir(scope, dir string) bool {
+	if IsMarkerScope(scope) {
+		_, ok := MarkerRootForDir(scope, dir)
+		return ok
+	}
+	if IsGitScope(scope) {
+		gitScope, _, ok := GitScopeForDir(dir)
+		return ok && gitScope == scope
+	}
 	if IsPatternScope(scope) {
-		matched, err := doublestar.Match(scope, dir)
-		if err != nil {
-			return false
-		}
-		return matched
+		includes, excludes := scopePatterns(scope)
+		return matchesAnyPattern(includes, dir) && !matchesAnyPattern(excludes, dir)
 	}
 	return scope == dir
 }
+
+func validateScope(scope string) error {
+	if scope == "" {
+		return fmt.Errorf("scope cannot be empty")
+	}
+	if IsMarkerScope(scope) {
+		marker := MarkerScopeFile(scope)
+		if strings.ContainsRune(marker, filepath.Separator) || marker == "." || marker == ".." {
+			return fmt.Errorf("marker scope must name a file, e.g. marker:go.mod")
+		}
+		return nil
+	}
+	if IsPatternScope(scope) {
+		includes, excludes := scopePatterns(scope)
+		if len(includes) == 0 {
+			return fmt.Errorf("pattern scope '%s' needs at least one pattern that is not excluded with '!'", scope)
+		}
+		for _, pattern := range append(includes, excludes...) {
+			if pattern == "" || !doublestar.ValidatePattern(pattern) {
+				return fmt.Errorf("invalid pattern '%s' in scope '%s'", pattern, scope)
+			}
+		}
+		return nil
+	}
+	if scope != "global" && !IsMatchedScope(scope) && !filepath.IsAbs(scope) {
+		return fmt.Errorf("scope must be 'global', a glob pattern, a git:host/org/repo scope, a marker:FILE scope, or an absolute directory path")
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMarkerRootForDir(t *testing.T) {
+	const marker = "scripto-test.mod"
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "project")
+	nested := filepath.Join(root, "tools", "lint")
+	for _, dir := range []string{root, nested} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, marker), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{filepath.Join(root, "cmd", "app"), filepath.Join(nested, "rules"), filepath.Join(tmp, "elsewhere")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		dir      string
+		wantRoot string
+	}{
+		{"marker directory", root, root},
+		{"below the marker", filepath.Join(root, "cmd", "app"), root},
+		{"between markers", filepath.Join(root, "tools"), root},
+		{"nested marker", nested, nested},
+		{"below the nested marker", filepath.Join(nested, "rules"), nested},
+		{"no marker above", filepath.Join(tmp, "elsewhere"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := MarkerRootForDir(markerScopePrefix+marker, tt.dir)
+			if got != tt.wantRoot || ok != (tt.wantRoot != "") {
+				t.Errorf("MarkerRootForDir(%q) = %q, %v, want %q", tt.dir, got, ok, tt.wantRoot)
+			}
+			if ScopeMatchesDir(markerScopePrefix+marker, tt.dir) != ok {
+				t.Errorf("expected ScopeMatchesDir to agree with MarkerRootForDir for %q", tt.dir)
+			}
+		})
+	}
+}
+
+func TestIsPatternScope(t *testing.T) {
+	tests := []struct {
+		scope string
+		want  bool
+	}{
+		{"global", false},
+		{"/home/me/work", false},
+		{"/data/a,b", false},
+		{"/data/a, b", false},
+		{"~/work/**", true},
+		{"/srv/app-?", true},
+		{"/srv/[ab]", true},
+		{"/srv/{web,api}", true},
+		{"/srv/*,!/srv/legacy", true},
+		{"/data/a,!/data/a/tmp", true},
+		{"git:github.com/org/repo", false},
+		{"marker:go.mod", false},
+	}
+	for _, tt := range tests {
+		if got := IsPatternScope(tt.scope); got != tt.want {
+			t.Errorf("IsPatternScope(%q) = %v, want %v", tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestScopePatterns(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	includes, excludes := scopePatterns("~/work/**, !~/work/legacy/**,/srv/{web,api},!/srv/[ab],x")
+	wantIncludes := []string{filepath.Join(home, "work/**"), "/srv/{web,api}", "x"}
+	wantExcludes := []string{filepath.Join(home, "work/legacy/**"), "/srv/[ab]"}
+	if !slices.Equal(includes, wantIncludes) {
+		t.Errorf("expected includes %v, got %v", wantIncludes, includes)
+	}
+	if !slices.Equal(excludes, wantExcludes) {
+		t.Errorf("expected excludes %v, got %v", wantExcludes, excludes)
+	}
+}
+
+func TestScopeMatchesDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		scope string
+		dir   string
+		want  bool
+	}{
+		{"/data/a,b", "/data/a,b", true},
+		{"/data/a,b", "/data/a", false},
+		{"/data/a,b", "/data/b", false},
+		{"~/work/**", filepath.Join(home, "work/app"), true},
+		{"~/work/**", "/work/app", false},
+		{"~/work/**,!~/work/legacy/**", filepath.Join(home, "work/app"), true},
+		{"~/work/**,!~/work/legacy/**", filepath.Join(home, "work/legacy/old"), false},
+		{"/srv/{web,api}", "/srv/api", true},
+		{"/srv/{web,api}", "/srv/db", false},
+		{"/data/*,!/data/tmp", "/data/tmp", false},
+		{"/data/*,!/data/tmp", "/data/app", true},
+		{"global", "/data", false},
+	}
+	for _, tt := range tests {
+		if got := ScopeMatchesDir(tt.scope, tt.dir); got != tt.want {
+			t.Errorf("ScopeMatchesDir(%q, %q) = %v, want %v", tt.scope, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestMarkerRootForDir_SeesNewMarker(t *testing.T) {
+	const marker = "scripto-new.mod"
+	root := t.TempDir()
+	dir := filepath.Join(root, "src")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := MarkerRootForDir(markerScopePrefix+marker, dir); ok {
+		t.Fatal("expected no marker root yet")
+	}
+
+	if err := os.WriteFile(filepath.Join(root, marker), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := MarkerRootForDir(markerScopePrefix+marker, dir); !ok || got != root {
+		t.Errorf("expected the new marker to be found at %q, got %q, %v", root, got, ok)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, marker), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	clearMarkerRootCache()
+	if got, _ := MarkerRootForDir(markerScopePrefix+marker, dir); got != dir {
+		t.Errorf("expected a cleared cache to find the nearer marker %q, got %q", dir, got)
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+var scopeVariableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// ScopeVariables returns a copy of the variables set on scope itself.
+func (s *ScriptService) ScopeVariables(scope string) map[string]string {
+	variables := make(map[string]string, len(s.scopeVariables[scope]))
+	for name, value := range s.scopeVariables[scope] {
+		variables[name] = value
+	}
+	return variables
+}
+
+// ScopesWithVariables returns the scopes that have variables set, sorted.
+func (s *ScriptService) ScopesWithVariables() []string {
+	scopes := make([]string, 0, len(s.scopeVariables))
+	for scope, variables := range s.scopeVariables {
+		if len(variables) > 0 {
+			scopes = append(scopes, scope)
+		}
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// ResolveScopeVariables merges the variables of every scope that applies in
+// dir along the hierarchy scripts are found in: global first, then matching
+// patterns, then parent directories from the root down, then dir itself. A
+// closer scope overrides a variable of the same name.
+func (s *ScriptService) ResolveScopeVariables(dir string) map[string]string {
+	scopes := []string{"global"}
+
+	var patterns []string
+	for scope := range s.scopeVariables {
+		if IsMatchedScope(scope) && ScopeMatchesDir(scope, dir) {
+			patterns = append(patterns, scope)
+		}
+	}
+	sort.Strings(patterns)
+	scopes = append(scopes, patterns...)
+
+	var parents []string
+	for current := dir; ; {
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		parents = append([]string{parent}, parents...)
+		current = parent
+	}
+	scopes = append(scopes, parents...)
+	scopes = append(scopes, dir)
+
+	merged := make(map[string]string)
+	for _, scope := range scopes {
+		for name, value := range s.scopeVariables[scope] {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// SetScopeVariable sets name to value for scripts run in scope.
+func (s *ScriptService) SetScopeVariable(scope, name, value string) error {
+	if !scopeVariableNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name '%s': use the placeholder name, e.g. Namespace", name)
+	}
+	return s.updateScopeVariables(scope, func(variables map[string]string) error {
+		variables[name] = value
+		return nil
+	})
+}
+
+// UnsetScopeVariable removes name from the variables of scope.
+func (s *ScriptService) UnsetScopeVariable(scope, name string) error {
+	return s.updateScopeVariables(scope, func(variables map[string]string) error {
+		if _, ok := variables[name]; !ok {
+			return fmt.Errorf("variable '%s' is not set in scope '%s'", name, scope)
+		}
+		delete(variables, name)
+		return nil
+	})
+}
+
+func (s *ScriptService) updateScopeVariables(scope string, change func(map[string]string) error) error {
+	if err := validateScope(scope); err != nil {
+		return err
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := ensureScope(tx, scope); err != nil {
+		return fmt.Errorf("failed to save scope: %w", err)
+	}
+	var data string
+	if err := tx.QueryRow("SELECT variables FROM scopes WHERE path = ?", scope).Scan(&data); err != nil {
+		return fmt.Errorf("failed to read scope variables: %w", err)
+	}
+	variables := decodeScopeVariables(data)
+	if err := change(variables); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(variables)
+	if err != nil {
+		return fmt.Errorf("failed to encode scope variables: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE scopes SET variables = ? WHERE path = ?", string(encoded), scope); err != nil {
+		return fmt.Errorf("failed to save scope variables: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit scope variables: %w", err)
+	}
+	return s.Reload()
+}
+
+func (s *ScriptService) loadScopeVariables() (map[string]map[string]string, error) {
+	rows, err := s.db.Query("SELECT path, variables FROM scopes WHERE variables != '{}'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scopeVariables := make(map[string]map[string]string)
+	for rows.Next() {
+		var scope, data string
+		if err := rows.Scan(&scope, &data); err != nil {
+			return nil, err
+		}
+		if variables := decodeScopeVariables(data); len(variables) > 0 {
+			scopeVariables[scope] = variables
+		}
+	}
+	return scopeVariables, rows.Err()
+}
+
+func decodeScopeVariables(data string) map[string]string {
+	variables := make(map[string]string)
+	if err := json.Unmarshal([]byte(data), &variables); err != nil {
+		return make(map[string]string)
+	}
+	return variables
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/vsuhanov/scripto/entities"
+)
+
+// NormalizeAliases trims aliases and drops empty entries, duplicates and the
+// script's own name. Aliases are typed on the command line like names, so they
+// cannot contain whitespace or commas or start with '-'.
+func NormalizeAliases(name string, aliases []string) ([]string, error) {
+	seen := map[string]bool{name: true}
+	var normalized []string
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" || seen[alias] {
+			continue
+		}
+		if strings.HasPrefix(alias, "-") || strings.ContainsFunc(alias, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		}) {
+			return nil, fmt.Errorf("invalid alias '%s': aliases cannot contain spaces or commas or start with '-'", alias)
+		}
+		seen[alias] = true
+		normalized = append(normalized, alias)
+	}
+	return normalized, nil
+}
+
+// cleanAliases normalizes hand-written aliases, such as those in a project
+// file, dropping invalid ones instead of failing.
+func cleanAliases(name string, aliases []string) []string {
+	var valid []string
+	for _, alias := range aliases {
+		if normalized, err := NormalizeAliases(name, []string{alias}); err == nil {
+			valid = append(valid, normalized...)
+		}
+	}
+	cleaned, _ := NormalizeAliases(name, valid)
+	return cleaned
+}
+
+// ParseAliases splits a comma or space separated list such as "dep, ship".
+func ParseAliases(input string) []string {
+	return strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// ScriptNames returns the name of script followed by its aliases.
+func ScriptNames(script *entities.Script) []string {
+	var names []string
+	if script.Name != "" {
+		names = append(names, script.Name)
+	}
+	return append(names, script.Aliases...)
+}
+
+// MatchesName reports whether input is the name or one of the aliases of
+// script.
+func MatchesName(script *entities.Script, input string) bool {
+	if input == "" {
+		return false
+	}
+	for _, name := range ScriptNames(script) {
+		if name == input {
+			return true
+		}
+	}
+	return false
+}
+
+// shortcutNames returns the names a shell shortcut is kept for. Only global
+// scripts get shortcuts.
+func shortcutNames(script *entities.Script) []string {
+	if script.Scope != "global" {
+		return nil
+	}
+	return ScriptNames(script)
+}
+
+func encodeAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return "[]"
+	}
+	data, _ := json.Marshal(aliases)
+	return string(data)
+}
+
+func decodeAliases(data string) []string {
+	var aliases []string
+	if err := json.Unmarshal([]byte(data), &aliases); err != nil || len(aliases) == 0 {
+		return nil
+	}
+	return aliases
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+const (
+	ImportSkip      = "skip"
+	ImportOverwrite = "overwrite"
+	ImportRename    = "rename"
+)
+
+const (
+	ImportActionAdded       = "added"
+	ImportActionOverwritten = "overwritten"
+	ImportActionRenamed     = "renamed"
+	ImportActionSkipped     = "skipped"
+)
+
+type ImportOptions struct {
+	// ScopeMap rewrites scope prefixes from the exporting machine, e.g.
+	// "/Users/alice/src" to "/home/bob/code".
+	ScopeMap map[string]string
+	// OnConflict decides what happens when the name or an alias of a script is
+	// already used in the destination scope: ImportSkip, ImportOverwrite or
+	// ImportRename. Overwriting and renaming drop incoming aliases that are
+	// still taken.
+	OnConflict string
+	// Source is the project config path to import into, or empty for the
+	// personal store.
+	Source string
+}
+
+type ImportedScript struct {
+	Script *entities.Script
+	Action string
+	// BundleName is the name the script had in the bundle; it differs from
+	// Script.Name when the script was renamed.
+	BundleName string
+}
+
+// ExportBundle collects scripts and their command bodies into a bundle.
+func (s *ScriptService) ExportBundle(scripts []*entities.Script) (*storage.Bundle, error) {
+	bundle := storage.NewBundle()
+	for _, script := range scripts {
+		command := ""
+		if script.FilePath != "" {
+			data, err := os.ReadFile(script.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read script '%s': %w", script.Name, err)
+			}
+			command = string(data)
+		}
+
+		scope := script.Scope
+		if script.OriginalScope != "" {
+			scope = script.OriginalScope
+		}
+
+		bundle.Scripts = append(bundle.Scripts, &storage.BundleScript{
+			ID:          script.ID,
+			Name:        script.Name,
+			Description: script.Description,
+			Scope:       scope,
+			Archived:    script.Archived,
+			Tags:        script.Tags,
+			Aliases:     script.Aliases,
+			AutoQuote:   script.AutoQuote,
+			Command:     command,
+		})
+	}
+	return bundle, nil
+}
+
+// MapScope rewrites scope using the longest matching prefix in mapping. A
+// prefix only matches whole path segments, so "/src" does not match "/srcs".
+func MapScope(scope string, mapping map[string]string) string {
+	prefixes := make([]string, 0, len(mapping))
+	for prefix := range mapping {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	for _, prefix := range prefixes {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		to := mapping[prefix]
+		if len(to) > 1 {
+			to = strings.TrimSuffix(to, "/")
+		}
+		if scope == prefix || scope == trimmed {
+			return to
+		}
+		if trimmed != "" && strings.HasPrefix(scope, trimmed+"/") {
+			return strings.TrimSuffix(to, "/") + scope[len(trimmed):]
+		}
+	}
+	return scope
+}
+
+// ImportBundle saves the scripts of bundle into the store selected by
+// opts.Source. Every entry is validated before anything is written.
+func (s *ScriptService) ImportBundle(bundle *storage.Bundle, opts ImportOptions) ([]ImportedScript, error) {
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = ImportSkip
+	case ImportSkip, ImportOverwrite, ImportRename:
+	default:
+		return nil, fmt.Errorf("invalid conflict policy '%s': expected skip, overwrite or rename", opts.OnConflict)
+	}
+
+	projectRoot := ""
+	if opts.Source != "" {
+		projectRoot = storage.ProjectStoreFromConfigPath(opts.Source).Root
+	}
+
+	scripts := make([]*entities.Script, len(bundle.Scripts))
+	for i, entry := range bundle.Scripts {
+		scope := MapScope(entry.Scope, opts.ScopeMap)
+		if projectRoot != "" {
+			scope = projectRoot
+		}
+		script := &entities.Script{
+			ID:          entry.ID,
+			Name:        entry.Name,
+			Description: entry.Description,
+			Scope:       scope,
+			Archived:    entry.Archived,
+			Tags:        entry.Tags,
+			Aliases:     entry.Aliases,
+			AutoQuote:   entry.AutoQuote,
+			Source:      opts.Source,
+		}
+		if err := s.ValidateScript(script); err != nil {
+			return nil, fmt.Errorf("script '%s' has scope '%s': %w; use --map-scope to rewrite it", entry.Name, entry.Scope, err)
+		}
+		scripts[i] = script
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	var results []ImportedScript
+	for i, script := range scripts {
+		entry := bundle.Scripts[i]
+		result, err := s.importScript(script, entry.Command, opts.OnConflict)
+		if err != nil {
+			return results, s.conflict(fmt.Errorf("failed to import '%s': %w", entry.Name, err))
+		}
+		result.BundleName = entry.Name
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (s *ScriptService) importScript(script *entities.Script, command, onConflict string) (ImportedScript, error) {
+	existing, err := s.scopeScripts(script.Source, script.Scope)
+	if err != nil {
+		return ImportedScript{}, fmt.Errorf("failed to read scripts: %w", err)
+	}
+
+	current := conflictingScript(existing, script)
+
+	action := ImportActionAdded
+	if current != nil {
+		switch onConflict {
+		case ImportSkip:
+			return ImportedScript{Script: current, Action: ImportActionSkipped}, nil
+		case ImportOverwrite:
+			script.ID = current.ID
+			script.Aliases = freeAliases(existing, script.Aliases, current)
+			if err := s.saveScript(script, command, current); err != nil {
+				return ImportedScript{}, err
+			}
+			return ImportedScript{Script: script, Action: ImportActionOverwritten}, nil
+		case ImportRename:
+			if nameTaken(existing, script.Name) {
+				script.Name = uniqueScriptName(existing, script.Name)
+			}
+			script.Aliases = freeAliases(existing, script.Aliases, nil)
+			action = ImportActionRenamed
+		}
+	}
+
+	// Keep the exported ID so history and later re-imports line up, unless
+	// that ID is already taken by another script here.
+	if script.ID != "" {
+		var taken int
+		if err := s.db.QueryRow("SELECT COUNT(*) FROM scripts WHERE id = ?", script.ID).Scan(&taken); err != nil {
+			return ImportedScript{}, fmt.Errorf("failed to check script id: %w", err)
+		}
+		if taken > 0 {
+			script.ID = uuid.New().String()
+		}
+	}
+
+	if err := s.saveScript(script, command, nil); err != nil {
+		return ImportedScript{}, err
+	}
+	return ImportedScript{Script: script, Action: action}, nil
+}
+
+// conflictingScript returns the script among existing that the name or an
+// alias of script collides with, as checkForDuplicateName would report it,
+// preferring one that the name collides with.
+func conflictingScript(existing []*entities.Script, script *entities.Script) *entities.Script {
+	for _, name := range ScriptNames(script) {
+		for _, e := range existing {
+			if MatchesName(e, name) {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+func nameTaken(existing []*entities.Script, name string) bool {
+	for _, e := range existing {
+		if MatchesName(e, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// freeAliases returns aliases without the ones already used as a name or
+// alias by a script in existing other than except.
+func freeAliases(existing []*entities.Script, aliases []string, except *entities.Script) []string {
+	var free []string
+	for _, alias := range aliases {
+		taken := false
+		for _, e := range existing {
+			if (except == nil || e.ID != except.ID) && MatchesName(e, alias) {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			free = append(free, alias)
+		}
+	}
+	return free
+}
+
+// uniqueScriptName suffixes name until it is neither the name nor an alias of
+// a script in existing.
+func uniqueScriptName(existing []*entities.Script, name string) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !nameTaken(existing, candidate) {
+			return candidate
+		}
+	}
+}
+
+// ParseScopeMapping parses "old=new" pairs as given to --map-scope. Both sides
+// are cleaned; the old side may be a path from another machine and is not
+// required to exist.
+func ParseScopeMapping(pairs []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid scope mapping '%s': expected /old/path=/new/path", pair)
+		}
+		if from != "global" {
+			from = filepath.Clean(from)
+		}
+		if to != "global" {
+			to = filepath.Clean(to)
+		}
+		mapping[from] = to
+	}
+	return mapping, nil
+}
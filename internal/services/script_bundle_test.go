@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+func TestMapScope(t *testing.T) {
+	mapping := map[string]string{
+		"/src":             "/home/me/code",
+		"/src/work/legacy": "/archive/legacy",
+		"/opt/tools/":      "/usr/local/tools/",
+		"global":           "/home/me",
+		"/mnt/root":        "/",
+	}
+	tests := []struct {
+		scope, expected string
+	}{
+		{"/src", "/home/me/code"},
+		{"/src/api", "/home/me/code/api"},
+		{"/srcs/api", "/srcs/api"},
+		{"/src/work/legacy", "/archive/legacy"},
+		{"/src/work/legacy/app", "/archive/legacy/app"},
+		{"/src/work/legacy2", "/home/me/code/work/legacy2"},
+		{"/opt/tools", "/usr/local/tools"},
+		{"/opt/tools/bin", "/usr/local/tools/bin"},
+		{"global", "/home/me"},
+		{"/global", "/global"},
+		{"/other", "/other"},
+		{"/mnt/root", "/"},
+		{"/mnt/root/etc", "/etc"},
+	}
+	for _, tt := range tests {
+		if got := MapScope(tt.scope, mapping); got != tt.expected {
+			t.Errorf("MapScope(%q) = %q, expected %q", tt.scope, got, tt.expected)
+		}
+	}
+}
+
+func TestParseScopeMapping(t *testing.T) {
+	mapping, err := ParseScopeMapping([]string{"/old/src/=/new/src", "global=/home/me/", "/a/../b=global"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"/old/src": "/new/src", "global": "/home/me", "/b": "global"}
+	if len(mapping) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, mapping)
+	}
+	for from, to := range expected {
+		if mapping[from] != to {
+			t.Errorf("expected %s to map to %s, got %q", from, to, mapping[from])
+		}
+	}
+
+	for _, pair := range []string{"/old", "=/new", "/old="} {
+		if _, err := ParseScopeMapping([]string{pair}); err == nil {
+			t.Errorf("expected %q to be rejected", pair)
+		}
+	}
+}
+
+func TestImportBundle_AliasConflicts(t *testing.T) {
+	s := newTestScriptService(t, "")
+	if err := s.SaveScript(&entities.Script{Name: "hello", Scope: "global", Aliases: []string{"hi"}}, "echo hello", nil); err != nil {
+		t.Fatal(err)
+	}
+	bundle := func() *storage.Bundle {
+		bundle := storage.NewBundle()
+		bundle.Scripts = []*storage.BundleScript{
+			{Name: "hi", Scope: "global", Command: "echo hi"},
+			{Name: "greet", Scope: "global", Aliases: []string{"hello", "yo"}, Command: "echo greet"},
+		}
+		return bundle
+	}
+
+	results, err := s.ImportBundle(bundle(), ImportOptions{OnConflict: ImportSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Action != ImportActionSkipped {
+			t.Errorf("expected %s to be skipped, got %s", result.BundleName, result.Action)
+		}
+	}
+
+	results, err = s.ImportBundle(bundle(), ImportOptions{OnConflict: ImportRename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected both scripts to be imported, got %d", len(results))
+	}
+	if got := results[0].Script; got.Name != "hi-2" || results[0].Action != ImportActionRenamed {
+		t.Errorf("expected hi to be renamed past the alias, got %q (%s)", got.Name, results[0].Action)
+	}
+	if got := results[1].Script; got.Name != "greet" || len(got.Aliases) != 1 || got.Aliases[0] != "yo" {
+		t.Errorf("expected greet to keep only its free alias, got %q %v", got.Name, got.Aliases)
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vsuhanov/scripto/entities"
+)
+
+type ScriptRevision struct {
+	ScriptID  string
+	Revision  int
+	CreatedAt time.Time
+	Body      string
+	BodyHash  string
+	Metadata  entities.Script
+}
+
+func revisionMetadata(script *entities.Script) string {
+	snapshot := *script
+	if snapshot.OriginalScope != "" {
+		snapshot.Scope = snapshot.OriginalScope
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// recordRevision appends a revision for script unless its body and metadata
+// match the latest recorded revision.
+func recordRevision(tx sqlExecer, script *entities.Script, body string) error {
+	if script.ID == "" {
+		return nil
+	}
+
+	hash := sha256hex(body)
+	metadata := revisionMetadata(script)
+
+	var latest int
+	var latestHash, latestMetadata string
+	err := tx.QueryRow(
+		"SELECT revision, body_hash, metadata FROM script_revisions WHERE script_id = ? ORDER BY revision DESC LIMIT 1",
+		script.ID,
+	).Scan(&latest, &latestHash, &latestMetadata)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read latest revision: %w", err)
+	}
+	if err == nil && latestHash == hash && latestMetadata == metadata {
+		return nil
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO script_revisions (script_id, revision, created_at, body, body_hash, metadata)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		script.ID, latest+1, time.Now().Unix(), body, hash, metadata,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// syncRevisions records a revision for every script whose file no longer
+// matches its latest revision, so edits made outside of scripto (an external
+// editor, a git pull of a project file) are kept as well.
+func (s *ScriptService) syncRevisions(config map[string][]*entities.Script) error {
+	rows, err := s.db.Query(
+		`SELECT r.script_id, r.body_hash FROM script_revisions r
+		 WHERE r.revision = (SELECT MAX(revision) FROM script_revisions WHERE script_id = r.script_id)`,
+	)
+	if err != nil {
+		return err
+	}
+	latest := make(map[string]string)
+	for rows.Next() {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return err
+		}
+		latest[id] = hash
+	}
+	rows.Close()
+
+	type pending struct {
+		script *entities.Script
+		body   string
+		edited bool
+	}
+	var changed []pending
+	for _, scripts := range config {
+		for _, script := range scripts {
+			if script.ID == "" || script.FilePath == "" {
+				continue
+			}
+			data, err := os.ReadFile(script.FilePath)
+			if err != nil {
+				continue
+			}
+			hash, ok := latest[script.ID]
+			if ok && hash == sha256hex(string(data)) {
+				continue
+			}
+			changed = append(changed, pending{script: script, body: string(data), edited: ok})
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, p := range changed {
+		var exists int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM scripts WHERE id = ?", p.script.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			continue
+		}
+		if err := recordRevision(tx, p.script, p.body); err != nil {
+			return err
+		}
+		// The file was edited outside scripto; count that as an update.
+		if p.edited {
+			if info, err := os.Stat(p.script.FilePath); err == nil {
+				if _, err := tx.Exec("UPDATE scripts SET updated_at = ? WHERE id = ?", info.ModTime().Unix(), p.script.ID); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *ScriptService) GetRevisions(scriptID string) ([]ScriptRevision, error) {
+	rows, err := s.db.Query(
+		`SELECT script_id, revision, created_at, body, body_hash, metadata
+		 FROM script_revisions WHERE script_id = ? ORDER BY revision DESC`,
+		scriptID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []ScriptRevision
+	for rows.Next() {
+		var r ScriptRevision
+		var createdAt int64
+		var metadata string
+		if err := rows.Scan(&r.ScriptID, &r.Revision, &createdAt, &r.Body, &r.BodyHash, &metadata); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = time.Unix(createdAt, 0)
+		_ = json.Unmarshal([]byte(metadata), &r.Metadata)
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *ScriptService) GetRevision(scriptID string, revision int) (*ScriptRevision, error) {
+	revisions, err := s.GetRevisions(scriptID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if revisions[i].Revision == revision {
+			return &revisions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d not found", revision)
+}
+
+// RollbackScript restores the body, name and description of an earlier
+// revision. The rollback itself is saved as a new revision.
+func (s *ScriptService) RollbackScript(script *entities.Script, revision int) (*entities.Script, error) {
+	rev, err := s.GetRevision(script.ID, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *script
+	if updated.OriginalScope != "" {
+		updated.Scope = updated.OriginalScope
+		updated.OriginalScope = ""
+	}
+	updated.Name = rev.Metadata.Name
+	updated.Description = rev.Metadata.Description
+
+	if err := s.SaveScript(&updated, rev.Body, script); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
@@ -1,192 +1,468 @@
 package services
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/vsuhanov/scripto/entities"
 	"github.com/vsuhanov/scripto/internal/storage"
+	"github.com/vsuhanov/scripto/internal/templatex"
 )
 
 type ScriptService struct {
+	db         *sql.DB
 	configPath string
 	config     storage.Config
+	projects   []*storage.ProjectStore
+	// scopeVariables maps a scope to the variables set on it.
+	scopeVariables map[string]map[string]string
+
+	projectHashes map[string]string
+	// projectIDs holds the IDs handed out on load to project entries that
+	// had none or a duplicate one, in file order, until a change to the file
+	// writes them.
+	projectIDs map[string][]string
+	locked     bool
 }
 
-func NewScriptService() (*ScriptService, error) {
+func NewScriptService(db *sql.DB) (*ScriptService, error) {
 	configPath, err := storage.GetConfigPath()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get config path: %w", err)
 	}
 
-	config, err := storage.ReadConfig(configPath)
+	service := &ScriptService{
+		db:         db,
+		configPath: configPath,
+	}
+	if _, err := service.importLegacyConfig(); err != nil {
+		return nil, fmt.Errorf("failed to import %s: %w", configPath, err)
+	}
+	if err := service.load(); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+func (s *ScriptService) load() error {
+	config, err := s.loadPersonalScripts()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
+		return fmt.Errorf("failed to read scripts: %w", err)
 	}
 
-	return &ScriptService{
-		configPath: configPath,
-		config:     config,
+	projectHashes := make(map[string]string)
+	projectIDs := make(map[string][]string)
+	var projects []*storage.ProjectStore
+	if cwd, err := os.Getwd(); err == nil {
+		projects, err = storage.FindProjectStores(cwd)
+		if err != nil {
+			return fmt.Errorf("failed to discover project stores: %w", err)
+		}
+	}
+
+	seenIDs := make(map[string]bool)
+	for _, scripts := range config {
+		for _, script := range scripts {
+			seenIDs[script.ID] = true
+		}
+	}
+
+	for _, project := range projects {
+		projectConfig, ids, err := s.readProjectWithIDs(project, seenIDs)
+		if err != nil {
+			return fmt.Errorf("failed to read project config: %w", err)
+		}
+		if ids != nil {
+			projectIDs[project.ConfigPath()] = ids
+		}
+		if err := s.registerProjectScripts(project, projectConfig.Scripts); err != nil {
+			return fmt.Errorf("failed to register project scripts: %w", err)
+		}
+		config[project.Root] = append(config[project.Root], projectConfig.Scripts...)
+		projectHashes[project.ConfigPath()] = projectConfig.Hash
+	}
+
+	if err := s.syncRevisions(config); err != nil {
+		log.Printf("Warning: failed to record external script changes: %v", err)
+	}
+	if err := s.backfillTimestamps(); err != nil {
+		log.Printf("Warning: failed to backfill script timestamps: %v", err)
+	}
+	if err := s.loadScriptState(config); err != nil {
+		return fmt.Errorf("failed to read script state: %w", err)
+	}
+	if err := s.loadTags(config); err != nil {
+		return fmt.Errorf("failed to read script tags: %w", err)
+	}
+	scopeVariables, err := s.loadScopeVariables()
+	if err != nil {
+		return fmt.Errorf("failed to read scope variables: %w", err)
+	}
+
+	s.config = config
+	s.projects = projects
+	s.projectHashes = projectHashes
+	s.projectIDs = projectIDs
+	s.scopeVariables = scopeVariables
+	return nil
+}
+
+func (s *ScriptService) saveScriptFile(source, name, command string) (string, error) {
+	if source == "" {
+		return storage.SaveScriptToFile(name, command)
+	}
+	return storage.SaveScriptToDir(storage.ProjectStoreFromConfigPath(source).ScriptsDir(), name, command)
+}
+
+// ProjectStores returns the project stores visible from the working directory,
+// nearest first.
+func (s *ScriptService) ProjectStores() []*storage.ProjectStore {
+	return s.projects
+}
+
+// ProjectTarget returns the project store new project scripts should be saved
+// to: the nearest existing one, or a new store in the working directory.
+func (s *ScriptService) ProjectTarget() *storage.ProjectStore {
+	if len(s.projects) > 0 {
+		return s.projects[0]
+	}
+	return storage.NewProjectStore(s.GetCurrentDirectoryScope())
+}
+
+// conflict reloads the in-memory snapshot so the caller can retry against the
+// latest state, and passes err through.
+func (s *ScriptService) conflict(err error) error {
+	if errors.Is(err, ErrConflict) {
+		if reloadErr := s.Reload(); reloadErr != nil {
+			return fmt.Errorf("%w (reload failed: %v)", err, reloadErr)
+		}
+	}
+	return err
+}
+
+// lock takes the scripts lock unless this service already holds it, so locked
+// operations can call each other and reload without deadlocking.
+func (s *ScriptService) lock() (func(), error) {
+	if s.locked {
+		return func() {}, nil
+	}
+	lock, err := storage.LockScripts()
+	if err != nil {
+		return nil, err
+	}
+	s.locked = true
+	return func() {
+		s.locked = false
+		lock.Unlock()
 	}, nil
 }
 
+// GetScript returns a copy of the script with the given ID as it is stored,
+// with its real scope rather than any contextual one.
+func (s *ScriptService) GetScript(id string) (*entities.Script, error) {
+	if id == "" {
+		return nil, fmt.Errorf("script has no ID")
+	}
+	for scope, scripts := range s.config {
+		for _, script := range scripts {
+			if script.ID == id {
+				copied := *script
+				copied.Scope = scope
+				copied.OriginalScope = ""
+				return &copied, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no script with ID '%s': %w", id, ErrScriptNotFound)
+}
+
+// storedScript resolves script by ID. The caller's version is kept so that
+// edits based on a stale copy are still detected.
+func (s *ScriptService) storedScript(script *entities.Script) (*entities.Script, error) {
+	stored, err := s.GetScript(script.ID)
+	if err != nil {
+		return nil, err
+	}
+	if script.Version != 0 {
+		stored.Version = script.Version
+	}
+	return stored, nil
+}
+
 func (s *ScriptService) SaveScript(script *entities.Script, command string, originalScript *entities.Script) error {
-	config, err := storage.ReadConfig(s.configPath)
+	unlock, err := s.lock()
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
+		return err
 	}
+	defer unlock()
 
 	if originalScript != nil {
-		if err := s.removeScriptFromConfig(config, originalScript); err != nil {
-			return fmt.Errorf("failed to remove old script: %w", err)
+		originalScript, err = s.storedScript(originalScript)
+		if err != nil {
+			return err
 		}
 	}
+	return s.conflict(s.saveScript(script, command, originalScript))
+}
+
+func (s *ScriptService) saveScript(script *entities.Script, command string, originalScript *entities.Script) error {
+	if script.Source != "" {
+		script.Scope = storage.ProjectStoreFromConfigPath(script.Source).Root
+	}
 
 	if script.Scope == "" {
 		return fmt.Errorf("scope cannot be empty")
 	}
 
-	if err := s.checkForDuplicateName(config, script); err != nil {
+	if err := templatex.CheckContextFields(command); err != nil {
+		return err
+	}
+
+	tags, err := NormalizeTags(script.Tags)
+	if err != nil {
 		return err
 	}
+	script.Tags = tags
+
+	aliases, err := NormalizeAliases(script.Name, script.Aliases)
+	if err != nil {
+		return err
+	}
+	script.Aliases = aliases
+
+	existing, err := s.scopeScripts(script.Source, script.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to read scripts: %w", err)
+	}
+	if err := s.checkForDuplicateName(existing, script, originalScript); err != nil {
+		return err
+	}
+
+	if originalScript != nil {
+		script.ID = originalScript.ID
+	} else if script.ID == "" {
+		script.ID = uuid.New().String()
+	} else if err := s.checkIDAvailable(script.ID); err != nil {
+		return err
+	}
+
+	movingStores := originalScript != nil && originalScript.Source != script.Source
 
 	var filePath string
-	if originalScript != nil && originalScript.FilePath != "" {
+	if !movingStores && originalScript != nil && originalScript.FilePath != "" {
 		filePath = originalScript.FilePath
-	} else if script.FilePath != "" {
+	} else if !movingStores && script.FilePath != "" {
 		filePath = script.FilePath
 	} else {
 		var err error
-		filePath, err = storage.SaveScriptToFile(script.Name, command)
+		filePath, err = s.saveScriptFile(script.Source, script.Name, command)
 		if err != nil {
 			return fmt.Errorf("failed to save script to file: %w", err)
 		}
 	}
 
 	script.FilePath = filePath
+	script.UpdatedAt = time.Now()
+	if script.CreatedAt.IsZero() {
+		script.CreatedAt = script.UpdatedAt
+	}
 
-	if config[script.Scope] == nil {
-		config[script.Scope] = []*entities.Script{}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
-	config[script.Scope] = append(config[script.Scope], script)
+	defer tx.Rollback()
 
-	if err := storage.WriteConfig(s.configPath, config); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	if originalScript != nil {
+		if err := checkVersion(tx, originalScript); err != nil {
+			return err
+		}
 	}
 
-	if err := os.WriteFile(script.FilePath, []byte(command), 0644); err != nil {
-		return fmt.Errorf("failed to update script file: %w", err)
+	if err := upsertScriptRow(tx, script); err != nil {
+		return fmt.Errorf("failed to save script: %w", err)
+	}
+	if err := saveScriptTags(tx, script.ID, script.Tags); err != nil {
+		return err
 	}
 
-	if script.Scope == "global" && script.Name != "" {
-		if originalScript != nil && originalScript.Name != "" && originalScript.Name != script.Name {
-			if err := storage.RemoveShortcutFunction(originalScript.Name); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to remove old shortcut for '%s': %v\n", originalScript.Name, err)
+	if originalScript != nil && originalScript.FilePath != "" {
+		if previous, err := os.ReadFile(originalScript.FilePath); err == nil {
+			if err := recordRevision(tx, originalScript, string(previous)); err != nil {
+				return err
 			}
 		}
-		
-		if err := storage.CreateShortcutFunction(script.Name); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to create shortcut for '%s': %v\n", script.Name, err)
+	}
+	if err := recordRevision(tx, script, command); err != nil {
+		return err
+	}
+
+	if movingStores && originalScript.Source != "" {
+		if err := s.updateProjectFile(originalScript.Source, func(config *storage.ProjectConfig) error {
+			return s.removeProjectScript(config, originalScript)
+		}); err != nil {
+			return err
 		}
 	}
 
-	return nil
-}
+	if script.Source != "" {
+		if err := s.updateProjectFile(script.Source, func(config *storage.ProjectConfig) error {
+			if originalScript != nil && !movingStores {
+				if err := s.removeProjectScript(config, originalScript); err != nil {
+					return fmt.Errorf("failed to remove old script: %w", err)
+				}
+			}
+			config.Scripts = append(config.Scripts, script)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
 
-func (s *ScriptService) DeleteScript(script *entities.Script) error {
-	config, err := storage.ReadConfig(s.configPath)
-	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
+	if err := storage.WriteFileAtomic(script.FilePath, []byte(command), 0644); err != nil {
+		return fmt.Errorf("failed to update script file: %w", err)
 	}
 
-	if err := s.removeScriptFromConfig(config, script); err != nil {
-		return fmt.Errorf("failed to remove script from config: %w", err)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit script: %w", err)
 	}
 
-	if err := storage.WriteConfig(s.configPath, config); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	if movingStores && originalScript.FilePath != "" && originalScript.FilePath != script.FilePath {
+		if err := os.Remove(originalScript.FilePath); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove old script file '%s': %v\n", originalScript.FilePath, err)
+		}
 	}
 
-	if script.FilePath != "" {
-		if err := os.Remove(script.FilePath); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to remove script file: %w", err)
+	shortcuts := shortcutNames(script)
+	if originalScript != nil {
+		kept := make(map[string]bool, len(shortcuts))
+		for _, name := range shortcuts {
+			kept[name] = true
+		}
+		for _, name := range shortcutNames(originalScript) {
+			if kept[name] {
+				continue
+			}
+			if err := storage.RemoveShortcutFunction(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to remove old shortcut for '%s': %v\n", name, err)
+			}
 		}
 	}
 
-	if script.Scope == "global" && script.Name != "" {
-		if err := storage.RemoveShortcutFunction(script.Name); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to remove shortcut for '%s': %v\n", script.Name, err)
+	for _, name := range shortcuts {
+		if err := storage.CreateShortcutFunction(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to create shortcut for '%s': %v\n", name, err)
 		}
 	}
 
-	return nil
+	return s.Reload()
 }
 
-func (s *ScriptService) ArchiveScript(script *entities.Script) error {
-	config, err := storage.ReadConfig(s.configPath)
+// DeleteScript moves script to the trash. Use PurgeScript to remove it for
+// good.
+func (s *ScriptService) DeleteScript(script *entities.Script) error {
+	unlock, err := s.lock()
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
+		return err
 	}
+	defer unlock()
 
-	scripts, exists := config[script.Scope]
-	if !exists {
-		return fmt.Errorf("script scope not found in config")
+	stored, err := s.storedScript(script)
+	if err != nil {
+		return err
 	}
+	return s.conflict(s.trashScript(stored))
+}
 
-	found := false
-	for _, configScript := range scripts {
-		if s.scriptsMatch(configScript, script) {
-			configScript.Archived = true
-			found = true
-			break
-		}
-	}
+func (s *ScriptService) ArchiveScript(script *entities.Script) error {
+	return s.setArchived(script, true)
+}
+
+func (s *ScriptService) UnarchiveScript(script *entities.Script) error {
+	return s.setArchived(script, false)
+}
 
-	if !found {
-		return fmt.Errorf("script not found in config")
+func (s *ScriptService) setArchived(script *entities.Script, archived bool) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	if err := storage.WriteConfig(s.configPath, config); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	stored, err := s.storedScript(script)
+	if err != nil {
+		return err
 	}
+	return s.conflict(s.updateArchived(stored, archived))
+}
 
-	s.config = config
-	return nil
+func (s *ScriptService) PinScript(script *entities.Script) error {
+	return s.setPinned(script, true)
 }
 
-func (s *ScriptService) UnarchiveScript(script *entities.Script) error {
-	config, err := storage.ReadConfig(s.configPath)
+func (s *ScriptService) UnpinScript(script *entities.Script) error {
+	return s.setPinned(script, false)
+}
+
+// setPinned marks a script as pinned. Pins are personal, so they are kept in
+// the database even for project scripts and do not count as an edit.
+func (s *ScriptService) setPinned(script *entities.Script, pinned bool) error {
+	unlock, err := s.lock()
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
+		return err
 	}
+	defer unlock()
 
-	scripts, exists := config[script.Scope]
-	if !exists {
-		return fmt.Errorf("script scope not found in config")
+	stored, err := s.GetScript(script.ID)
+	if err != nil {
+		return err
 	}
+	result, err := s.db.Exec("UPDATE scripts SET pinned = ? WHERE id = ? AND deleted_at IS NULL", pinned, stored.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update script: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("script not found")
+	}
+	return s.Reload()
+}
 
-	found := false
-	for _, configScript := range scripts {
-		if s.scriptsMatch(configScript, script) {
-			configScript.Archived = false
-			found = true
-			break
+func (s *ScriptService) updateArchived(script *entities.Script, archived bool) error {
+	if script.Source != "" {
+		if err := s.updateProjectFile(script.Source, func(config *storage.ProjectConfig) error {
+			configScript := s.findProjectScript(config, script)
+			if configScript == nil {
+				return fmt.Errorf("script not found in project config")
+			}
+			configScript.Archived = archived
+			return nil
+		}); err != nil {
+			return err
 		}
 	}
 
-	if !found {
-		return fmt.Errorf("script not found in config")
+	if err := checkVersion(s.db, script); err != nil {
+		return err
 	}
 
-	if err := storage.WriteConfig(s.configPath, config); err != nil {
-		return fmt.Errorf("failed to save config: %w", err)
+	result, err := s.db.Exec("UPDATE scripts SET archived = ?, version = version + 1 WHERE id = ?", archived, script.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update script: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 && script.Source == "" {
+		return fmt.Errorf("script not found")
 	}
 
-	s.config = config
-	return nil
+	return s.Reload()
 }
 
 func (s *ScriptService) FindAllScopesScriptsWithArchived() ([]*entities.Script, error) {
@@ -209,7 +485,7 @@ func (s *ScriptService) FindAllScopesScriptsWithArchived() ([]*entities.Script,
 
 	var patternScopes []string
 	for scope := range s.config {
-		if !seen[scope] && IsPatternScope(scope) && ScopeMatchesDir(scope, cwd) {
+		if !seen[scope] && IsMatchedScope(scope) && ScopeMatchesDir(scope, cwd) {
 			patternScopes = append(patternScopes, scope)
 		}
 	}
@@ -281,71 +557,58 @@ func (s *ScriptService) CreateEmptyScript() *entities.Script {
 }
 
 func (s *ScriptService) ValidateScript(script *entities.Script) error {
-	if script.Scope == "" {
-		return fmt.Errorf("scope cannot be empty")
+	if err := validateScope(script.Scope); err != nil {
+		return err
 	}
 
-	if script.Scope != "global" && !IsPatternScope(script.Scope) {
-		if !filepath.IsAbs(script.Scope) {
-			return fmt.Errorf("scope must be 'global', a glob pattern, or an absolute directory path")
-		}
+	if _, err := NormalizeTags(script.Tags); err != nil {
+		return err
+	}
+
+	if _, err := NormalizeAliases(script.Name, script.Aliases); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func (s *ScriptService) removeScriptFromConfig(config storage.Config, script *entities.Script) error {
-	lookupScope := script.Scope
-	if _, exists := config[lookupScope]; !exists && script.OriginalScope != "" {
-		lookupScope = script.OriginalScope
+// checkIDAvailable fails if id already belongs to a script. A trashed script
+// keeps its ID so that it can be restored under it.
+func (s *ScriptService) checkIDAvailable(id string) error {
+	if _, err := s.GetScript(id); err == nil {
+		return fmt.Errorf("script ID '%s' is already in use", id)
 	}
-	scripts, exists := config[lookupScope]
-	if !exists {
-		return fmt.Errorf("script scope not found in config")
+	var taken int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM scripts WHERE id = ? AND deleted_at IS NULL", id).Scan(&taken); err != nil {
+		return fmt.Errorf("failed to check script ID: %w", err)
 	}
-
-	scriptRemoved := false
-	for i, configScript := range scripts {
-		if s.scriptsMatchInScope(configScript, script, lookupScope) {
-			config[lookupScope] = append(scripts[:i], scripts[i+1:]...)
-			if len(config[lookupScope]) == 0 {
-				delete(config, lookupScope)
-			}
-			scriptRemoved = true
-			break
-		}
-	}
-
-	if !scriptRemoved {
-		return fmt.Errorf("script not found in config")
+	if taken > 0 {
+		return fmt.Errorf("script ID '%s' is already in use", id)
 	}
-
 	return nil
 }
 
-func (s *ScriptService) scriptsMatch(script1, script2 *entities.Script) bool {
-	return script1.Name == script2.Name &&
-		script1.FilePath == script2.FilePath &&
-		script1.Description == script2.Description &&
-		script1.Scope == script2.Scope
-}
-
-func (s *ScriptService) scriptsMatchInScope(configScript, script *entities.Script, scope string) bool {
-	return configScript.Name == script.Name &&
-		configScript.FilePath == script.FilePath &&
-		configScript.Description == script.Description &&
-		configScript.Scope == scope
-}
-
-func (s *ScriptService) checkForDuplicateName(config storage.Config, script *entities.Script) error {
-	if script.Name == "" {
+// checkForDuplicateName fails if the name or an alias of script is already the
+// name or an alias of another script in the same scope.
+func (s *ScriptService) checkForDuplicateName(existing []*entities.Script, script, originalScript *entities.Script) error {
+	names := ScriptNames(script)
+	if len(names) == 0 {
 		return nil // Allow unnamed scripts
 	}
 
-	if scripts, exists := config[script.Scope]; exists {
-		for _, existingScript := range scripts {
-			if existingScript.Name == script.Name {
-				return fmt.Errorf("script with name '%s' already exists in scope '%s'", script.Name, script.Scope)
+	for _, existingScript := range existing {
+		if existingScript.ID == script.ID {
+			continue
+		}
+		if originalScript != nil && existingScript.ID == originalScript.ID {
+			continue
+		}
+		for _, name := range names {
+			if existingScript.Name == name {
+				return fmt.Errorf("script with name '%s' already exists in scope '%s'", name, script.Scope)
+			}
+			if MatchesName(existingScript, name) {
+				return fmt.Errorf("'%s' is already an alias of script '%s' in scope '%s'", name, existingScript.Name, script.Scope)
 			}
 		}
 	}
@@ -357,7 +620,14 @@ func (s *ScriptService) GetScopeDisplayName(scope string) string {
 	if scope == "global" {
 		return "global"
 	}
-	if IsPatternScope(scope) {
+	if IsGitScope(scope) {
+		// git:github.com/org/repo reads as git:org/repo
+		if _, path, ok := strings.Cut(strings.TrimPrefix(scope, gitScopePrefix), "/"); ok && strings.Contains(path, "/") {
+			return gitScopePrefix + path
+		}
+		return scope
+	}
+	if IsPatternScope(scope) || IsMarkerScope(scope) {
 		return scope
 	}
 	return filepath.Base(scope)
@@ -379,9 +649,9 @@ func (s *ScriptService) CreateTempScriptFile(command string) (string, error) {
 }
 
 func (s *ScriptService) SyncShortcuts() error {
-	config, err := storage.ReadConfig(s.configPath)
+	config, err := s.loadPersonalScripts()
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
+		return fmt.Errorf("failed to read scripts: %w", err)
 	}
 
 	if err := storage.SyncShortcuts(config); err != nil {
@@ -392,11 +662,11 @@ func (s *ScriptService) SyncShortcuts() error {
 }
 
 func (s *ScriptService) Reload() error {
-	config, err := storage.ReadConfig(s.configPath)
-	if err != nil {
+	clearGitRepoCache()
+	clearMarkerRootCache()
+	if err := s.load(); err != nil {
 		return fmt.Errorf("failed to reload config: %w", err)
 	}
-	s.config = config
 	return nil
 }
 
@@ -420,7 +690,7 @@ func (s *ScriptService) FindAllScripts() ([]*entities.Script, error) {
 
 	var patternScopes []string
 	for scope := range s.config {
-		if scope != cwd && IsPatternScope(scope) && ScopeMatchesDir(scope, cwd) {
+		if scope != cwd && IsMatchedScope(scope) && ScopeMatchesDir(scope, cwd) {
 			patternScopes = append(patternScopes, scope)
 		}
 	}
@@ -435,6 +705,18 @@ func (s *ScriptService) FindAllScripts() ([]*entities.Script, error) {
 		}
 	}
 
+	for _, project := range s.projects {
+		if project.Root == cwd {
+			continue
+		}
+		for _, scriptEnt := range s.config[project.Root] {
+			if scriptEnt.Archived || scriptEnt.Source == "" {
+				continue
+			}
+			results = append(results, scriptEnt)
+		}
+	}
+
 	if scripts, exists := s.config["global"]; exists {
 		for _, scriptEnt := range scripts {
 			if scriptEnt.Archived {
@@ -451,7 +733,7 @@ func (s *ScriptService) FindAllScripts() ([]*entities.Script, error) {
 func (s *ScriptService) FindContextualScripts(contextualIDs map[string]bool, cwd string, alreadyInHierarchy map[string]bool) ([]*entities.Script, error) {
 	var results []*entities.Script
 	for scope, scripts := range s.config {
-		if scope == "global" || alreadyInHierarchy[scope] || (IsPatternScope(scope) && ScopeMatchesDir(scope, cwd)) {
+		if scope == "global" || alreadyInHierarchy[scope] || (IsMatchedScope(scope) && ScopeMatchesDir(scope, cwd)) {
 			continue
 		}
 		for _, scriptEnt := range scripts {
@@ -506,7 +788,7 @@ func (s *ScriptService) MatchAllScopes(input string) ([]*entities.Script, error)
 	}
 	var matches []*entities.Script
 	for _, script := range allScripts {
-		if script.Name != "" && script.Name == input {
+		if MatchesName(script, input) {
 			matches = append(matches, script)
 		}
 	}
@@ -520,7 +802,7 @@ func (s *ScriptService) Match(input string) (*entities.Script, error) {
 	}
 
 	for _, script := range allScripts {
-		if script.Name != "" && script.Name == input {
+		if MatchesName(script, input) {
 			return script, nil
 		}
 	}
@@ -538,7 +820,7 @@ func (s *ScriptService) FilterByKeyword(keyword string) ([]*entities.Script, err
 	keyword = strings.ToLower(keyword)
 
 	for _, script := range allScripts {
-		searchText := strings.ToLower(script.Name + " " + script.Description)
+		searchText := strings.ToLower(strings.Join(ScriptNames(script), " ") + " " + script.Description)
 		if strings.Contains(searchText, keyword) {
 			filtered = append(filtered, script)
 		}
@@ -573,7 +855,7 @@ func (s *ScriptService) getScopePriority(scope string) int {
 		return 0 // Local (current directory)
 	}
 
-	if IsPatternScope(scope) {
+	if IsMatchedScope(scope) {
 		if ScopeMatchesDir(scope, cwd) {
 			return 0
 		}
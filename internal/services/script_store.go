@@ -0,0 +1,524 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+const legacyConfigImportedKey = "legacy_config_imported"
+
+// upsertScriptSQL keeps created_at of an existing row and only moves
+// updated_at forward when a timestamp is given.
+const upsertScriptSQL = `INSERT INTO scripts (id, scope_id, name, description, file_path, archived, source, aliases, auto_quote, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		scope_id = excluded.scope_id,
+		name = excluded.name,
+		description = excluded.description,
+		file_path = excluded.file_path,
+		archived = excluded.archived,
+		source = excluded.source,
+		aliases = excluded.aliases,
+		auto_quote = excluded.auto_quote,
+		updated_at = COALESCE(excluded.updated_at, scripts.updated_at),
+		version = scripts.version + 1,
+		deleted_at = NULL`
+
+var ErrConflict = errors.New("changed by another scripto process; reload and try again")
+
+var ErrScriptNotFound = errors.New("script not found")
+
+type sqlExecer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func ensureScope(tx sqlExecer, path string) (int64, error) {
+	if _, err := tx.Exec("INSERT INTO scopes (path) VALUES (?) ON CONFLICT(path) DO NOTHING", path); err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := tx.QueryRow("SELECT id FROM scopes WHERE path = ?", path).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func upsertScriptRow(tx sqlExecer, script *entities.Script) error {
+	scopeID, err := ensureScope(tx, script.Scope)
+	if err != nil {
+		return fmt.Errorf("failed to save scope: %w", err)
+	}
+	_, err = tx.Exec(upsertScriptSQL,
+		script.ID, scopeID, script.Name, script.Description, script.FilePath, script.Archived, script.Source,
+		encodeAliases(script.Aliases), script.AutoQuote, unixOrNull(script.CreatedAt), unixOrNull(script.UpdatedAt),
+	)
+	return err
+}
+
+// unixOrNull stores a zero time as NULL so that backfillTimestamps can fill it
+// in from the command file.
+func unixOrNull(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Unix()
+}
+
+func scanScripts(rows *sql.Rows) ([]*entities.Script, error) {
+	defer rows.Close()
+	var scripts []*entities.Script
+	for rows.Next() {
+		script := &entities.Script{}
+		var aliases string
+		if err := rows.Scan(&script.ID, &script.Name, &script.Description, &script.FilePath, &script.Archived, &script.Version, &script.Scope, &aliases, &script.AutoQuote); err != nil {
+			return nil, err
+		}
+		script.Aliases = decodeAliases(aliases)
+		scripts = append(scripts, script)
+	}
+	return scripts, rows.Err()
+}
+
+func (s *ScriptService) loadPersonalScripts() (storage.Config, error) {
+	rows, err := s.db.Query(
+		`SELECT s.id, s.name, s.description, s.file_path, s.archived, s.version, sc.path, s.aliases, s.auto_quote
+		 FROM scripts s JOIN scopes sc ON sc.id = s.scope_id
+		 WHERE s.source = '' AND s.deleted_at IS NULL
+		 ORDER BY s.rowid`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	scripts, err := scanScripts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	config := make(storage.Config)
+	for _, script := range scripts {
+		config[script.Scope] = append(config[script.Scope], script)
+	}
+	return config, nil
+}
+
+// scopeScripts returns the scripts already stored in scope within the store
+// identified by source.
+func (s *ScriptService) scopeScripts(source, scope string) ([]*entities.Script, error) {
+	if source != "" {
+		projectConfig, err := s.readProject(storage.ProjectStoreFromConfigPath(source))
+		if err != nil {
+			return nil, err
+		}
+		return projectConfig.Scripts, nil
+	}
+
+	rows, err := s.db.Query(
+		`SELECT s.id, s.name, s.description, s.file_path, s.archived, s.version, sc.path, s.aliases, s.auto_quote
+		 FROM scripts s JOIN scopes sc ON sc.id = s.scope_id
+		 WHERE s.source = '' AND s.deleted_at IS NULL AND sc.path = ?`,
+		scope,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanScripts(rows)
+}
+
+// registerProjectScripts mirrors the scripts of a project file into the
+// scripts table so execution history can reference them. Rows belonging to
+// personal scripts are never taken over, and trashed project scripts are kept
+// until they are restored or purged.
+func (s *ScriptService) registerProjectScripts(project *storage.ProjectStore, scripts []*entities.Script) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := []string{}
+	for _, script := range scripts {
+		if script.ID == "" {
+			continue
+		}
+		scopeID, err := ensureScope(tx, script.Scope)
+		if err != nil {
+			return err
+		}
+		script.Aliases = cleanAliases(script.Name, script.Aliases)
+		if _, err := tx.Exec(upsertScriptSQL+" WHERE scripts.source != ''",
+			script.ID, scopeID, script.Name, script.Description, script.FilePath, script.Archived, script.Source,
+			encodeAliases(script.Aliases), script.AutoQuote, nil, nil,
+		); err != nil {
+			return err
+		}
+		if err := saveScriptTags(tx, script.ID, cleanTags(script.Tags)); err != nil {
+			return err
+		}
+		ids = append(ids, script.ID)
+	}
+
+	idsJSON, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM scripts WHERE source = ? AND deleted_at IS NULL AND id NOT IN (SELECT value FROM json_each(?))",
+		project.ConfigPath(), string(idsJSON),
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// readProjectWithIDs reads a project file and makes sure every entry has an ID
+// that no personal script, nearer project or other entry uses. Offending
+// entries get an ID in memory only, since the file is usually shared; it
+// returns those IDs in file order, or nil when none were needed, for the next
+// change to the file to write. seen collects the IDs handed out so far.
+func (s *ScriptService) readProjectWithIDs(project *storage.ProjectStore, seen map[string]bool) (*storage.ProjectConfig, []string, error) {
+	config, err := storage.ReadProjectConfig(project)
+	if err != nil {
+		return nil, nil, err
+	}
+	needed := needsNewIDs(config.Scripts, seen)
+	assignScriptIDs(project.ConfigPath(), config.Scripts, seen)
+	if !needed {
+		return config, nil, nil
+	}
+	ids := make([]string, len(config.Scripts))
+	for i, script := range config.Scripts {
+		ids[i] = script.ID
+	}
+	return config, ids, nil
+}
+
+// readProject reads a project file with the IDs handed out when it was
+// loaded, as long as it has not changed since.
+func (s *ScriptService) readProject(project *storage.ProjectStore) (*storage.ProjectConfig, error) {
+	config, err := storage.ReadProjectConfig(project)
+	if err != nil {
+		return nil, err
+	}
+	source := project.ConfigPath()
+	if ids := s.projectIDs[source]; len(ids) == len(config.Scripts) && s.projectHashes[source] == config.Hash {
+		for i, script := range config.Scripts {
+			script.ID = ids[i]
+		}
+	}
+	return config, nil
+}
+
+func needsNewIDs(scripts []*entities.Script, seen map[string]bool) bool {
+	local := make(map[string]bool, len(scripts))
+	for _, script := range scripts {
+		if script.ID == "" || seen[script.ID] || local[script.ID] {
+			return true
+		}
+		local[script.ID] = true
+	}
+	return false
+}
+
+func assignScriptIDs(source string, scripts []*entities.Script, seen map[string]bool) {
+	for _, script := range scripts {
+		if script.ID == "" || seen[script.ID] {
+			script.ID = projectScriptID(source, script, seen)
+		}
+		seen[script.ID] = true
+	}
+}
+
+// projectScriptID derives an ID for a project entry from its file, name and
+// old ID, so the entry keeps its history and pins from one run to the next
+// before the ID is written to the file.
+func projectScriptID(source string, script *entities.Script, seen map[string]bool) string {
+	seed := source + "\x00" + script.Name + "\x00" + script.ID
+	for {
+		id := uuid.NewSHA1(uuid.NameSpaceURL, []byte(seed)).String()
+		if !seen[id] {
+			return id
+		}
+		seed += "\x00"
+	}
+}
+
+// backfillTimestamps gives scripts that have no timestamps yet, such as those
+// stored before timestamps were recorded or newly found in a project file, the
+// modification time of their command file.
+func (s *ScriptService) backfillTimestamps() error {
+	rows, err := s.db.Query("SELECT id, file_path FROM scripts WHERE created_at IS NULL OR updated_at IS NULL")
+	if err != nil {
+		return err
+	}
+	missing := make(map[string]string)
+	for rows.Next() {
+		var id, filePath string
+		if err := rows.Scan(&id, &filePath); err != nil {
+			rows.Close()
+			return err
+		}
+		missing[id] = filePath
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for id, filePath := range missing {
+		modTime := time.Now()
+		if info, err := os.Stat(filePath); err == nil {
+			modTime = info.ModTime()
+		}
+		if _, err := tx.Exec(
+			"UPDATE scripts SET created_at = COALESCE(created_at, ?), updated_at = COALESCE(updated_at, ?) WHERE id = ?",
+			modTime.Unix(), modTime.Unix(), id,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// loadScriptState copies the state kept only in the database, timestamps and
+// pins, onto the loaded scripts.
+func (s *ScriptService) loadScriptState(config storage.Config) error {
+	rows, err := s.db.Query("SELECT id, COALESCE(created_at, 0), COALESCE(updated_at, 0), pinned FROM scripts")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	type state struct {
+		created, updated int64
+		pinned           bool
+	}
+	byID := make(map[string]state)
+	for rows.Next() {
+		var id string
+		var st state
+		if err := rows.Scan(&id, &st.created, &st.updated, &st.pinned); err != nil {
+			return err
+		}
+		byID[id] = st
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, scripts := range config {
+		for _, script := range scripts {
+			if st, ok := byID[script.ID]; ok {
+				script.CreatedAt = unixTime(st.created)
+				script.UpdatedAt = unixTime(st.updated)
+				script.Pinned = st.pinned
+			}
+		}
+	}
+	return nil
+}
+
+func unixTime(seconds int64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
+
+// checkVersion fails with ErrConflict when the stored row no longer matches the
+// version the caller loaded.
+func checkVersion(tx sqlExecer, script *entities.Script) error {
+	if script.ID == "" || script.Source != "" || script.Version == 0 {
+		return nil
+	}
+	var version int
+	var trashed bool
+	err := tx.QueryRow("SELECT version, deleted_at IS NOT NULL FROM scripts WHERE id = ?", script.ID).Scan(&version, &trashed)
+	if err == sql.ErrNoRows || trashed {
+		return fmt.Errorf("script '%s' was deleted: %w", script.Name, ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check script version: %w", err)
+	}
+	if version != script.Version {
+		return fmt.Errorf("script '%s' was %w", script.Name, ErrConflict)
+	}
+	return nil
+}
+
+// updateProjectFile applies update to a project file. If the file changed on
+// disk since it was loaded, it fails with ErrConflict instead of overwriting
+// the other change.
+func (s *ScriptService) updateProjectFile(source string, update func(config *storage.ProjectConfig) error) error {
+	project := storage.ProjectStoreFromConfigPath(source)
+	config, err := s.readProject(project)
+	if err != nil {
+		return fmt.Errorf("failed to read project config: %w", err)
+	}
+	if loadedHash, ok := s.projectHashes[source]; ok && loadedHash != config.Hash {
+		return fmt.Errorf("project file %s was %w", source, ErrConflict)
+	}
+	if err := update(config); err != nil {
+		return err
+	}
+	if err := storage.WriteProjectConfig(project, config); err != nil {
+		return err
+	}
+	// The IDs handed out on load are in the file now.
+	delete(s.projectIDs, source)
+	// Our own write is not a concurrent change; let later updates in the same
+	// operation go through.
+	if _, ok := s.projectHashes[source]; ok {
+		if written, err := storage.ReadProjectConfig(project); err == nil {
+			s.projectHashes[source] = written.Hash
+		}
+	}
+	return nil
+}
+
+func (s *ScriptService) findProjectScriptIndex(config *storage.ProjectConfig, script *entities.Script) int {
+	for i, configScript := range config.Scripts {
+		if configScript.ID == script.ID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *ScriptService) findProjectScript(config *storage.ProjectConfig, script *entities.Script) *entities.Script {
+	if i := s.findProjectScriptIndex(config, script); i >= 0 {
+		return config.Scripts[i]
+	}
+	return nil
+}
+
+func (s *ScriptService) removeProjectScript(config *storage.ProjectConfig, script *entities.Script) error {
+	i := s.findProjectScriptIndex(config, script)
+	if i < 0 {
+		return fmt.Errorf("script not found in project config")
+	}
+	config.Scripts = append(config.Scripts[:i], config.Scripts[i+1:]...)
+	return nil
+}
+
+// importLegacyConfig copies scripts from scripts.json into the database the
+// first time scripto starts. 'scripto doctor --fix' imports entries added
+// later. It returns the number of scripts added.
+func (s *ScriptService) importLegacyConfig() (int, error) {
+	var value string
+	err := s.db.QueryRow("SELECT value FROM meta WHERE key = ?", legacyConfigImportedKey).Scan(&value)
+	if err == nil {
+		return 0, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to read import state: %w", err)
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	return s.importLegacy()
+}
+
+// importLegacy does the work of importLegacyConfig; the caller holds the lock.
+func (s *ScriptService) importLegacy() (int, error) {
+	config, err := storage.ReadConfig(s.configPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	scopes := make([]string, 0, len(config))
+	for scope := range config {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	imported := 0
+	seen := make(map[string]bool)
+	for _, scope := range scopes {
+		for _, script := range config[scope] {
+			script.Scope = scope
+			script.Source = ""
+
+			byID := script.ID != "" && !seen[script.ID]
+			exists, err := legacyScriptImported(tx, script, byID)
+			if err != nil {
+				return 0, err
+			}
+			if exists {
+				continue
+			}
+			if !byID {
+				script.ID = uuid.New().String()
+			}
+
+			if err := upsertScriptRow(tx, script); err != nil {
+				return 0, fmt.Errorf("failed to import script '%s': %w", script.Name, err)
+			}
+			if err := saveScriptTags(tx, script.ID, cleanTags(script.Tags)); err != nil {
+				return 0, err
+			}
+			seen[script.ID] = true
+			imported++
+		}
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
+		legacyConfigImportedKey, s.configPath,
+	); err != nil {
+		return 0, fmt.Errorf("failed to record import: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return imported, nil
+}
+
+// legacyScriptImported reports whether a scripts.json entry is already in the
+// database, matching by ID when byID is set and otherwise by scope, name and
+// file path.
+func legacyScriptImported(tx sqlExecer, script *entities.Script, byID bool) (bool, error) {
+	var exists int
+	if byID {
+		if err := tx.QueryRow("SELECT COUNT(*) FROM scripts WHERE id = ?", script.ID).Scan(&exists); err != nil {
+			return false, fmt.Errorf("failed to check script %s: %w", script.ID, err)
+		}
+		return exists > 0, nil
+	}
+	if err := tx.QueryRow(
+		`SELECT COUNT(*) FROM scripts s JOIN scopes sc ON sc.id = s.scope_id
+		 WHERE s.source = '' AND sc.path = ? AND s.name = ? AND s.file_path = ?`,
+		script.Scope, script.Name, script.FilePath,
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check script '%s': %w", script.Name, err)
+	}
+	return exists > 0, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+// newTestScriptService opens a ScriptService on a fresh data directory with
+// the given scripts.json content, run from an empty working directory.
+func newTestScriptService(t *testing.T, legacyConfig string) *ScriptService {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "scripts.json")
+	t.Setenv("HOME", dir)
+	t.Setenv("SCRIPTO_CONFIG", configPath)
+	t.Setenv("SCRIPTO_SQLITE_DB_PATH", "")
+	if legacyConfig != "" {
+		if err := os.WriteFile(configPath, []byte(legacyConfig), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	work := filepath.Join(dir, "work")
+	if err := os.MkdirAll(work, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(work)
+
+	db, err := storage.OpenSQLite()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	service, err := NewScriptService(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestImportLegacyConfig(t *testing.T) {
+	legacy := `{
+  "global": [
+    {"id": "a1", "name": "build", "description": "Build it", "file_path": "/tmp/build.sh", "tags": ["go"]},
+    {"name": "no-id", "file_path": "/tmp/noid.sh"}
+  ],
+  "/srv/app": [
+    {"id": "a1", "name": "duplicate id", "file_path": "/tmp/dup.sh", "archived": true}
+  ]
+}`
+	s := newTestScriptService(t, legacy)
+
+	scripts, err := s.FindAllScopesScriptsWithArchived()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]string)
+	for _, script := range scripts {
+		byName[script.Name] = script.Scope
+	}
+	if len(scripts) != 3 || byName["build"] != "global" || byName["no-id"] != "global" || byName["duplicate id"] != "/srv/app" {
+		t.Fatalf("expected the three entries in their scopes, got %v", byName)
+	}
+	kept, err := s.GetScript("a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept.Name != "duplicate id" || !kept.Archived {
+		t.Errorf("expected the first entry in scope order to keep the shared ID, got %+v", kept)
+	}
+	for _, script := range scripts {
+		if script.Name == "build" && (script.ID == "a1" || script.Description != "Build it" || len(script.Tags) != 1) {
+			t.Errorf("expected build to get a new ID and keep its metadata, got %+v", script)
+		}
+	}
+
+	// The import runs once; a second start does not add the entries again.
+	if n, err := s.importLegacyConfig(); err != nil || n != 0 {
+		t.Errorf("expected no second import, got %d (%v)", n, err)
+	}
+	// doctor --fix imports only what is new.
+	if n, err := s.importLegacy(); err != nil || n != 0 {
+		t.Errorf("expected already imported entries to be skipped, got %d (%v)", n, err)
+	}
+}
+
+func TestProjectIDs_AssignedInMemory(t *testing.T) {
+	s := newTestScriptService(t, "")
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	project := storage.NewProjectStore(cwd)
+	if err := os.MkdirAll(project.ScriptsDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(project.ScriptsDir(), "lint.sh"), []byte("make lint"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	original := []byte(`{"scripts": [{"name": "lint", "file_path": "scripts/lint.sh"}]}`)
+	if err := os.WriteFile(project.ConfigPath(), original, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	projectScript := func() *entities.Script {
+		t.Helper()
+		if err := s.Reload(); err != nil {
+			t.Fatal(err)
+		}
+		for _, script := range s.config[cwd] {
+			if script.Name == "lint" {
+				return script
+			}
+		}
+		t.Fatal("expected the project script to be loaded")
+		return nil
+	}
+
+	first := projectScript()
+	if first.ID == "" {
+		t.Fatal("expected the project script to get an ID")
+	}
+	if second := projectScript(); second.ID != first.ID {
+		t.Errorf("expected the same ID on every load, got %s and %s", first.ID, second.ID)
+	}
+	if data, _ := os.ReadFile(project.ConfigPath()); string(data) != string(original) {
+		t.Errorf("expected loading to leave the project file alone, got %s", data)
+	}
+
+	issues, err := s.RunDoctor(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := 0
+	for _, issue := range issues {
+		if issue.Kind == IssueMissingID {
+			missing++
+		}
+	}
+	if missing != 1 {
+		t.Errorf("expected doctor to report the missing ID, got %d issues", missing)
+	}
+
+	if err := s.ArchiveScript(first); err != nil {
+		t.Fatal(err)
+	}
+	written, err := storage.ReadProjectConfig(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written.Scripts) != 1 || written.Scripts[0].ID != first.ID || !written.Scripts[0].Archived {
+		t.Errorf("expected the change to write the ID handed out on load, got %+v", written.Scripts[0])
+	}
+}
+
+func TestSaveScript_RejectsUnknownContextField(t *testing.T) {
+	s := newTestScriptService(t, "")
+	err := s.SaveScript(&entities.Script{Name: "push", Scope: "global"}, "git push origin {{ .Scripto.Brnach }}", nil)
+	if err == nil || !strings.Contains(err.Error(), "valid fields are Cwd, GitRoot, GitBranch") {
+		t.Fatalf("expected the unknown field to be rejected, got %v", err)
+	}
+	if scripts, _ := s.FindAllScopesScriptsWithArchived(); len(scripts) != 0 {
+		t.Errorf("expected nothing to be saved, got %d scripts", len(scripts))
+	}
+}
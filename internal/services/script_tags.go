@@ -0,0 +1,179 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+// NormalizeTags trims tags, drops a leading '#', lowercases them and removes
+// duplicates and empty entries. Tags may contain letters, digits and - _ . : /
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if !validTag(tag) {
+			return nil, fmt.Errorf("invalid tag '%s': use letters, digits and - _ . : /", tag)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// cleanTags normalizes hand-written tags, such as those in a project file,
+// dropping invalid ones instead of failing.
+func cleanTags(tags []string) []string {
+	var valid []string
+	for _, tag := range tags {
+		if normalized, err := NormalizeTags([]string{tag}); err == nil {
+			valid = append(valid, normalized...)
+		}
+	}
+	cleaned, _ := NormalizeTags(valid)
+	return cleaned
+}
+
+// ParseTags splits a comma or space separated list such as "k8s, #db".
+func ParseTags(input string) ([]string, error) {
+	return NormalizeTags(strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}))
+}
+
+func validTag(tag string) bool {
+	for _, r := range tag {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r > 127:
+		case strings.ContainsRune("-_.:/", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// HasTags reports whether script carries every tag in tags.
+func HasTags(script *entities.Script, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, t := range script.Tags {
+			if t == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// AllTags returns every tag used by a visible script, sorted.
+func (s *ScriptService) AllTags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, scripts := range s.config {
+		for _, script := range scripts {
+			for _, tag := range script.Tags {
+				if !seen[tag] {
+					seen[tag] = true
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// ScriptIDsWithTag returns the IDs of scripts tagged with tag.
+func (s *ScriptService) ScriptIDsWithTag(tag string) (map[string]bool, error) {
+	rows, err := s.db.Query(
+		`SELECT st.script_id FROM script_tags st JOIN tags t ON t.id = st.tag_id WHERE t.name = ?`,
+		strings.ToLower(strings.TrimPrefix(tag, "#")),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// saveScriptTags replaces the tags of a script row.
+func saveScriptTags(tx sqlExecer, scriptID string, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM script_tags WHERE script_id = ?", scriptID); err != nil {
+		return fmt.Errorf("failed to clear tags: %w", err)
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT INTO tags (name) VALUES (?) ON CONFLICT(name) DO NOTHING", tag); err != nil {
+			return fmt.Errorf("failed to save tag '%s': %w", tag, err)
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO script_tags (script_id, tag_id) SELECT ?, id FROM tags WHERE name = ?",
+			scriptID, tag,
+		); err != nil {
+			return fmt.Errorf("failed to tag script: %w", err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM script_tags)"); err != nil {
+		return fmt.Errorf("failed to remove unused tags: %w", err)
+	}
+	return nil
+}
+
+func (s *ScriptService) tagsByScriptID() (map[string][]string, error) {
+	rows, err := s.db.Query(
+		`SELECT st.script_id, t.name FROM script_tags st JOIN tags t ON t.id = st.tag_id ORDER BY t.name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string][]string)
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], name)
+	}
+	return tags, rows.Err()
+}
+
+// loadTags sets the tags of the loaded scripts from the database, where the
+// tags of project scripts are mirrored on registration.
+func (s *ScriptService) loadTags(config storage.Config) error {
+	tags, err := s.tagsByScriptID()
+	if err != nil {
+		return err
+	}
+	for _, scripts := range config {
+		for _, script := range scripts {
+			script.Tags = tags[script.ID]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+const defaultTrashRetentionDays = 30
+
+// TrashedScript is a script that was deleted but can still be restored. Its
+// body is the latest recorded revision, since the command file itself is
+// removed when the script is trashed.
+type TrashedScript struct {
+	Script    *entities.Script
+	Body      string
+	DeletedAt time.Time
+}
+
+// TrashRetention returns how long trashed scripts are kept before they are
+// purged automatically. Zero means they are kept until purged by hand.
+func TrashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if value := os.Getenv("SCRIPTO_TRASH_RETENTION_DAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Printf("Warning: invalid SCRIPTO_TRASH_RETENTION_DAYS %q, using %d", value, defaultTrashRetentionDays)
+		} else {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func (s *ScriptService) trashScript(script *entities.Script) error {
+	body := ""
+	if script.FilePath != "" {
+		data, err := os.ReadFile(script.FilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read script file: %w", err)
+		}
+		body = string(data)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkVersion(tx, script); err != nil {
+		return err
+	}
+
+	if err := recordRevision(tx, script, body); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(
+		"UPDATE scripts SET deleted_at = ?, version = version + 1 WHERE id = ? AND deleted_at IS NULL",
+		time.Now().Unix(), script.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move script to trash: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("script not found")
+	}
+
+	if script.Source != "" {
+		if err := s.updateProjectFile(script.Source, func(config *storage.ProjectConfig) error {
+			return s.removeProjectScript(config, script)
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit script removal: %w", err)
+	}
+
+	if script.FilePath != "" {
+		if err := os.Remove(script.FilePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove script file: %w", err)
+		}
+	}
+
+	for _, name := range shortcutNames(script) {
+		if err := storage.RemoveShortcutFunction(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove shortcut for '%s': %v\n", name, err)
+		}
+	}
+
+	return s.Reload()
+}
+
+// GetTrash returns trashed scripts, most recently deleted first.
+func (s *ScriptService) GetTrash() ([]*TrashedScript, error) {
+	rows, err := s.db.Query(
+		`SELECT s.id, s.name, s.description, s.file_path, s.archived, s.version, sc.path, s.source, s.aliases, s.auto_quote, s.deleted_at,
+		        COALESCE((SELECT body FROM script_revisions r WHERE r.script_id = s.id ORDER BY r.revision DESC LIMIT 1), '')
+		 FROM scripts s JOIN scopes sc ON sc.id = s.scope_id
+		 WHERE s.deleted_at IS NOT NULL
+		 ORDER BY s.deleted_at DESC, s.rowid DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trash []*TrashedScript
+	for rows.Next() {
+		script := &entities.Script{}
+		var aliases string
+		var deletedAt int64
+		var body string
+		if err := rows.Scan(&script.ID, &script.Name, &script.Description, &script.FilePath, &script.Archived,
+			&script.Version, &script.Scope, &script.Source, &aliases, &script.AutoQuote, &deletedAt, &body); err != nil {
+			return nil, err
+		}
+		script.Aliases = decodeAliases(aliases)
+		trash = append(trash, &TrashedScript{Script: script, Body: body, DeletedAt: time.Unix(deletedAt, 0)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.tagsByScriptID()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range trash {
+		t.Script.Tags = tags[t.Script.ID]
+	}
+	return trash, nil
+}
+
+func (s *ScriptService) GetTrashedScript(id string) (*TrashedScript, error) {
+	trash, err := s.GetTrash()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range trash {
+		if t.Script.ID == id {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("script %s is not in the trash", id)
+}
+
+// RestoreScript brings a trashed script back into the store it was deleted
+// from. It fails if another script with the same name now exists there.
+func (s *ScriptService) RestoreScript(id string) (*entities.Script, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	trashed, err := s.GetTrashedScript(id)
+	if err != nil {
+		return nil, err
+	}
+
+	script := trashed.Script
+	script.Version = 0
+	if script.FilePath != "" {
+		if _, err := os.Stat(script.FilePath); err == nil {
+			script.FilePath = ""
+		}
+	}
+
+	if err := s.saveScript(script, trashed.Body, nil); err != nil {
+		return nil, s.conflict(err)
+	}
+	return script, nil
+}
+
+// PurgeScript permanently removes a trashed script and its revisions. It
+// fails with ErrConflict if the script was restored and trashed again since
+// it was read.
+func (s *ScriptService) PurgeScript(script *entities.Script) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var version int
+	err = s.db.QueryRow("SELECT version FROM scripts WHERE id = ? AND deleted_at IS NOT NULL", script.ID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("script %s is not in the trash", script.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to purge script: %w", err)
+	}
+	if script.Version != 0 && version != script.Version {
+		return fmt.Errorf("script '%s' was %w", script.Name, ErrConflict)
+	}
+
+	if _, err := s.db.Exec("DELETE FROM scripts WHERE id = ? AND deleted_at IS NOT NULL", script.ID); err != nil {
+		return fmt.Errorf("failed to purge script: %w", err)
+	}
+	return nil
+}
+
+// PurgeTrash empties the trash and returns the number of scripts removed.
+func (s *ScriptService) PurgeTrash() (int, error) {
+	return s.purgeTrashedBefore(nil)
+}
+
+// PurgeExpiredTrash removes the scripts that have been in the trash for
+// longer than TrashRetention and returns how many it removed.
+func (s *ScriptService) PurgeExpiredTrash() (int, error) {
+	retention := TrashRetention()
+	if retention == 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().Add(-retention)
+	return s.purgeTrashedBefore(&cutoff)
+}
+
+func (s *ScriptService) purgeTrashedBefore(cutoff *time.Time) (int, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	var result sql.Result
+	if cutoff == nil {
+		result, err = s.db.Exec("DELETE FROM scripts WHERE deleted_at IS NOT NULL")
+	} else {
+		result, err = s.db.Exec("DELETE FROM scripts WHERE deleted_at IS NOT NULL AND deleted_at < ?", cutoff.Unix())
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	affected, _ := result.RowsAffected()
+	return int(affected), nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vsuhanov/scripto/entities"
+)
+
+func TestTrashRetention(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 30 * 24 * time.Hour},
+		{"7", 7 * 24 * time.Hour},
+		{"0", 0},
+		{"-1", 30 * 24 * time.Hour},
+		{"a week", 30 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Setenv("SCRIPTO_TRASH_RETENTION_DAYS", tt.value)
+		if got := TrashRetention(); got != tt.want {
+			t.Errorf("TrashRetention() with %q = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// trashTestScript saves a global script and moves it to the trash.
+func trashTestScript(t *testing.T, s *ScriptService, name string) *entities.Script {
+	t.Helper()
+	script := &entities.Script{Name: name, Scope: "global"}
+	if err := s.SaveScript(script, "echo "+name, nil); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.GetScript(script.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteScript(stored); err != nil {
+		t.Fatal(err)
+	}
+	trashed, err := s.GetTrashedScript(script.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trashed.Script
+}
+
+func TestRestoreScript(t *testing.T) {
+	s := newTestScriptService(t, "")
+	trashed := trashTestScript(t, s, "deploy")
+
+	restored, err := s.RestoreScript(trashed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID != trashed.ID || restored.Name != "deploy" {
+		t.Errorf("expected deploy to come back with its ID, got %+v", restored)
+	}
+	if trash, _ := s.GetTrash(); len(trash) != 0 {
+		t.Errorf("expected the trash to be empty, got %d scripts", len(trash))
+	}
+	if _, err := s.GetScript(trashed.ID); err != nil {
+		t.Errorf("expected the restored script to be found: %v", err)
+	}
+}
+
+func TestRestoreScript_NameTaken(t *testing.T) {
+	s := newTestScriptService(t, "")
+	trashed := trashTestScript(t, s, "deploy")
+	if err := s.SaveScript(&entities.Script{Name: "deploy", Scope: "global"}, "echo new", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.RestoreScript(trashed.ID)
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected the restore to fail on the taken name, got %v", err)
+	}
+	if _, err := s.GetTrashedScript(trashed.ID); err != nil {
+		t.Errorf("expected the script to stay in the trash: %v", err)
+	}
+}
+
+func TestPurgeScript(t *testing.T) {
+	s := newTestScriptService(t, "")
+	trashed := trashTestScript(t, s, "deploy")
+
+	stale := *trashed
+	stale.Version--
+	if err := s.PurgeScript(&stale); !errors.Is(err, ErrConflict) {
+		t.Errorf("expected a stale version to conflict, got %v", err)
+	}
+
+	if err := s.PurgeScript(trashed); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetTrashedScript(trashed.ID); err == nil {
+		t.Errorf("expected the script to be gone from the trash")
+	}
+	if err := s.PurgeScript(trashed); err == nil || !strings.Contains(err.Error(), "not in the trash") {
+		t.Errorf("expected purging twice to fail, got %v", err)
+	}
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	s := newTestScriptService(t, "")
+	old := trashTestScript(t, s, "old")
+	recent := trashTestScript(t, s, "recent")
+	if _, err := s.db.Exec("UPDATE scripts SET deleted_at = ? WHERE id = ?", time.Now().Add(-10*24*time.Hour).Unix(), old.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SCRIPTO_TRASH_RETENTION_DAYS", "0")
+	if purged, err := s.PurgeExpiredTrash(); err != nil || purged != 0 {
+		t.Errorf("expected a retention of 0 to keep the trash, got %d, %v", purged, err)
+	}
+
+	t.Setenv("SCRIPTO_TRASH_RETENTION_DAYS", "7")
+	purged, err := s.PurgeExpiredTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Errorf("expected 1 expired script to be purged, got %d", purged)
+	}
+	if _, err := s.GetTrashedScript(recent.ID); err != nil {
+		t.Errorf("expected the recent script to stay in the trash: %v", err)
+	}
+
+	if purged, err := s.PurgeTrash(); err != nil || purged != 1 {
+		t.Errorf("expected PurgeTrash to remove the rest, got %d, %v", purged, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/vsuhanov/scripto/internal/templatex"
 	"github.com/vsuhanov/scripto/internal/tui/colors"
 	"github.com/vsuhanov/scripto/internal/utils"
 
@@ -31,9 +32,13 @@ type ExitCommand struct {
 }
 
 type ExecuteScriptCommand struct {
-	Command           string
+	Command string
+	// Display is the command as printed, with its secrets redacted; Command
+	// is printed when it is empty.
+	Display           string
 	Name              string
 	PlaceholderValues map[string]string
+	Secrets           map[string]string
 	WorkingDir        string
 	WriteHistory      bool
 }
@@ -64,8 +69,11 @@ func (ts *TerminalService) PrepareExit(code int) TerminalServiceCommand {
 	return &ExitCommand{Code: code}
 }
 
-func (ts *TerminalService) PrepareScriptExecution(command, name string, placeholderValues map[string]string, workingDir string, writeHistory bool) TerminalServiceCommand {
-	return &ExecuteScriptCommand{Command: command, Name: name, PlaceholderValues: placeholderValues, WorkingDir: workingDir, WriteHistory: writeHistory}
+// PrepareScriptExecution prepares command to be run by the shell, printing
+// display in its place. The values in secrets are hidden from the printed
+// command and the shell history.
+func (ts *TerminalService) PrepareScriptExecution(command, display, name string, placeholderValues, secrets map[string]string, workingDir string, writeHistory bool) TerminalServiceCommand {
+	return &ExecuteScriptCommand{Command: command, Display: display, Name: name, PlaceholderValues: placeholderValues, Secrets: secrets, WorkingDir: workingDir, WriteHistory: writeHistory}
 }
 
 func (ts *TerminalService) PrepareExternalEditing(scriptPath string) TerminalServiceCommand {
@@ -81,7 +89,11 @@ func (ts *TerminalService) ExecuteCommand(cmd TerminalServiceCommand) {
 	case *ExitCommand:
 		ts.exitFunc(c.Code)
 	case *ExecuteScriptCommand:
-		ts.executeScriptCommand(c.Command, c.Name, c.PlaceholderValues, c.WorkingDir, c.WriteHistory)
+		display := c.Display
+		if display == "" {
+			display = c.Command
+		}
+		ts.executeScriptCommand(c.Command, display, c.Name, c.PlaceholderValues, c.Secrets, c.WorkingDir, c.WriteHistory)
 	case *EditScriptExternalCommand:
 		ts.editScriptExternalCommand(c.ScriptPath)
 	}
@@ -139,9 +151,9 @@ func printScriptBox(command, name string) {
 	fmt.Fprintln(os.Stderr, boxStyle.Render(content))
 }
 
-func (ts *TerminalService) executeScriptCommand(command, name string, placeholderValues map[string]string, workingDir string, writeHistory bool) {
+func (ts *TerminalService) executeScriptCommand(command, display, name string, placeholderValues, secrets map[string]string, workingDir string, writeHistory bool) {
 	if utils.IsStderrTerminal() {
-		printScriptBox(command, name)
+		printScriptBox(templatex.Redact(display, secrets), name)
 	}
 	cmdFdPath := ts.options.targetCommandFile
 	if cmdFdPath != "" {
@@ -151,7 +163,7 @@ func (ts *TerminalService) executeScriptCommand(command, name string, placeholde
 		}
 		if writeHistory && name != "" {
 			cwd, _ := os.Getwd()
-			richEntry := buildRichHistoryEntry(name, placeholderValues, workingDir, cwd)
+			richEntry := buildRichHistoryEntry(name, placeholderValues, secrets, workingDir, cwd)
 			if richEntry != "" {
 				content += "\nprint -s " + shellescape(richEntry)
 			} else {
@@ -167,14 +179,20 @@ func (ts *TerminalService) executeScriptCommand(command, name string, placeholde
 	ts.exitFunc(int(exitCodeSuccess))
 }
 
-func buildRichHistoryEntry(name string, values map[string]string, workingDir, cwd string) string {
-	if len(values) == 0 && (workingDir == "" || workingDir == cwd) {
-		return ""
-	}
-	entry := "scripto " + name + " --"
+// buildRichHistoryEntry returns a scripto invocation that repeats the run.
+// Secret values are left out, so running it again asks for them.
+func buildRichHistoryEntry(name string, values, secrets map[string]string, workingDir, cwd string) string {
+	var args string
 	for k, v := range values {
-		entry += " --" + k + "=" + shellQuoteValue(v)
+		if _, secret := secrets[k]; secret {
+			continue
+		}
+		args += " --" + k + "=" + shellQuoteValue(v)
+	}
+	if args == "" && (workingDir == "" || workingDir == cwd) {
+		return ""
 	}
+	entry := "scripto " + name + " --" + args
 	if workingDir != "" && workingDir != cwd {
 		entry += " --working-dir=" + shellQuoteValue(workingDir)
 	}
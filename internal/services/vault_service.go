@@ -0,0 +1,210 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+// VaultPassphraseEnv unlocks the vault without prompting.
+const VaultPassphraseEnv = "SCRIPTO_VAULT_PASSPHRASE"
+
+// ErrVaultLocked is returned when a secret is needed before the vault has been
+// unlocked with its passphrase.
+var ErrVaultLocked = errors.New("vault is locked")
+
+// ErrSecretNotFound is returned when no secret with the name applies.
+var ErrSecretNotFound = errors.New("secret not found")
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// VaultService keeps named secrets in a passphrase-encrypted file. Secrets are
+// scoped like scripts, so the one for the current directory wins over a
+// pattern, parent directory or global secret of the same name.
+type VaultService struct {
+	path       string
+	passphrase string
+	secrets    []*storage.VaultSecret
+	unlocked   bool
+}
+
+func NewVaultService() *VaultService {
+	path, _ := storage.GetVaultPath()
+	return &VaultService{path: path}
+}
+
+// Exists reports whether a vault file has been created.
+func (v *VaultService) Exists() bool {
+	_, err := os.Stat(v.path)
+	return err == nil
+}
+
+// Unlock decrypts the vault with passphrase and keeps it open for the rest of
+// the process. A vault that does not exist yet is created with passphrase on
+// the first write.
+func (v *VaultService) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase cannot be empty")
+	}
+	secrets, err := storage.ReadVault(v.path, passphrase)
+	if err != nil {
+		return err
+	}
+	v.passphrase = passphrase
+	v.secrets = secrets
+	v.unlocked = true
+	return nil
+}
+
+// ensureUnlocked opens the vault with VaultPassphraseEnv when it is set. A
+// missing vault needs no passphrase to be read.
+func (v *VaultService) ensureUnlocked() error {
+	if v.unlocked {
+		return nil
+	}
+	if passphrase := os.Getenv(VaultPassphraseEnv); passphrase != "" {
+		return v.Unlock(passphrase)
+	}
+	if !v.Exists() {
+		return nil
+	}
+	return ErrVaultLocked
+}
+
+// List returns every secret, sorted by name and scope.
+func (v *VaultService) List() ([]*storage.VaultSecret, error) {
+	if err := v.ensureUnlocked(); err != nil {
+		return nil, err
+	}
+	secrets := append([]*storage.VaultSecret(nil), v.secrets...)
+	sort.Slice(secrets, func(i, j int) bool {
+		if secrets[i].Name != secrets[j].Name {
+			return secrets[i].Name < secrets[j].Name
+		}
+		return secrets[i].Scope < secrets[j].Scope
+	})
+	return secrets, nil
+}
+
+// Get returns the secret stored under name in exactly scope.
+func (v *VaultService) Get(name, scope string) (*storage.VaultSecret, error) {
+	if err := v.ensureUnlocked(); err != nil {
+		return nil, err
+	}
+	for _, secret := range v.secrets {
+		if secret.Name == name && secret.Scope == scope {
+			return secret, nil
+		}
+	}
+	return nil, fmt.Errorf("no secret '%s' in scope '%s': %w", name, scope, ErrSecretNotFound)
+}
+
+// Set stores value under name in scope, replacing an existing value.
+func (v *VaultService) Set(name, scope, value string) error {
+	if !secretNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name '%s': use letters, digits and _ . -", name)
+	}
+	if err := validateScope(scope); err != nil {
+		return err
+	}
+	return v.update(func(secrets []*storage.VaultSecret) ([]*storage.VaultSecret, error) {
+		for _, secret := range secrets {
+			if secret.Name == name && secret.Scope == scope {
+				secret.Value = value
+				return secrets, nil
+			}
+		}
+		return append(secrets, &storage.VaultSecret{Name: name, Scope: scope, Value: value}), nil
+	})
+}
+
+// Remove deletes the secret stored under name in scope.
+func (v *VaultService) Remove(name, scope string) error {
+	return v.update(func(secrets []*storage.VaultSecret) ([]*storage.VaultSecret, error) {
+		for i, secret := range secrets {
+			if secret.Name == name && secret.Scope == scope {
+				return append(secrets[:i], secrets[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("no secret '%s' in scope '%s': %w", name, scope, ErrSecretNotFound)
+	})
+}
+
+// update re-reads the vault under the scripts lock, applies change and writes
+// it back, so concurrent scripto processes do not lose each other's secrets.
+func (v *VaultService) update(change func([]*storage.VaultSecret) ([]*storage.VaultSecret, error)) error {
+	if err := v.ensureUnlocked(); err != nil {
+		return err
+	}
+	if v.passphrase == "" {
+		return ErrVaultLocked
+	}
+
+	lock, err := storage.LockScripts()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	secrets, err := storage.ReadVault(v.path, v.passphrase)
+	if err != nil {
+		return err
+	}
+	secrets, err = change(secrets)
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteVault(v.path, v.passphrase, secrets); err != nil {
+		return err
+	}
+	v.secrets = secrets
+	return nil
+}
+
+// Resolve returns the value of the secret name that applies in dir: one
+// scoped to dir itself, then a matching pattern, then the nearest parent
+// directory and finally a global one.
+func (v *VaultService) Resolve(name, dir string) (string, error) {
+	if err := v.ensureUnlocked(); err != nil {
+		return "", err
+	}
+
+	byScope := make(map[string]string)
+	var patterns []string
+	for _, secret := range v.secrets {
+		if secret.Name != name {
+			continue
+		}
+		byScope[secret.Scope] = secret.Value
+		if IsMatchedScope(secret.Scope) && ScopeMatchesDir(secret.Scope, dir) {
+			patterns = append(patterns, secret.Scope)
+		}
+	}
+
+	if value, ok := byScope[dir]; ok {
+		return value, nil
+	}
+	if len(patterns) > 0 {
+		sort.Strings(patterns)
+		return byScope[patterns[0]], nil
+	}
+	for current := dir; ; {
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		if value, ok := byScope[parent]; ok {
+			return value, nil
+		}
+		current = parent
+	}
+	if value, ok := byScope["global"]; ok {
+		return value, nil
+	}
+	return "", fmt.Errorf("no secret '%s' for %s, add it with 'scripto secret set %s': %w", name, dir, name, ErrSecretNotFound)
+}
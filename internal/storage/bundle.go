@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+)
+
+const (
+	BundleFormatJSON = "json"
+	BundleFormatTar  = "tar"
+
+	bundleVersion      = 1
+	bundleManifestName = "bundle.json"
+)
+
+// Bundle is a portable set of scripts with their command bodies, used to move
+// scripts between machines. Scopes are kept as they were on the exporting
+// machine and are rewritten on import.
+type Bundle struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Scripts    []*BundleScript `json:"scripts"`
+}
+
+type BundleScript struct {
+	ID          string   `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Scope       string   `json:"scope"`
+	Archived    bool     `json:"archived,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+	AutoQuote   bool     `json:"auto_quote,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	// File is the body's path inside a tar bundle; Command is empty then.
+	File string `json:"file,omitempty"`
+}
+
+func NewBundle() *Bundle {
+	return &Bundle{Version: bundleVersion, ExportedAt: time.Now().UTC(), Scripts: []*BundleScript{}}
+}
+
+func WriteBundle(w io.Writer, bundle *Bundle, format string) error {
+	switch format {
+	case BundleFormatJSON:
+		data, err := json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case BundleFormatTar:
+		return writeTarBundle(w, bundle)
+	default:
+		return fmt.Errorf("unknown bundle format '%s': expected json or tar", format)
+	}
+}
+
+func writeTarBundle(w io.Writer, bundle *Bundle) error {
+	tw := tar.NewWriter(w)
+	manifest := *bundle
+	manifest.Scripts = make([]*BundleScript, 0, len(bundle.Scripts))
+	bodies := make(map[string]string)
+
+	for i, script := range bundle.Scripts {
+		entry := *script
+		base := script.Name
+		if base == "" {
+			base = "script"
+		}
+		entry.File = path.Join("scripts", fmt.Sprintf("%03d_%s.sh", i+1, SanitizeForFilename(base)))
+		bodies[entry.File] = script.Command
+		entry.Command = ""
+		manifest.Scripts = append(manifest.Scripts, &entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, bundleManifestName, data, bundle.ExportedAt); err != nil {
+		return err
+	}
+	for _, entry := range manifest.Scripts {
+		if err := writeTarFile(tw, entry.File, []byte(bodies[entry.File]), bundle.ExportedAt); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// ReadBundle reads a JSON or tar bundle, detecting the format from its content.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	var bundle *Bundle
+	if isTar(data) {
+		bundle, err = readTarBundle(data)
+	} else {
+		bundle = &Bundle{}
+		if err = json.Unmarshal(data, bundle); err != nil {
+			err = fmt.Errorf("failed to parse bundle: %w", err)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bundle.Version > bundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than supported version %d", bundle.Version, bundleVersion)
+	}
+	return bundle, nil
+}
+
+func isTar(data []byte) bool {
+	return len(data) >= 262 && bytes.Equal(data[257:262], []byte("ustar"))
+}
+
+func readTarBundle(data []byte) (*Bundle, error) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar bundle: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", header.Name, err)
+		}
+		files[path.Clean(header.Name)] = content
+	}
+
+	manifest, ok := files[bundleManifestName]
+	if !ok {
+		return nil, fmt.Errorf("tar bundle has no %s", bundleManifestName)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(manifest, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", bundleManifestName, err)
+	}
+
+	for _, script := range bundle.Scripts {
+		if script.File == "" {
+			continue
+		}
+		body, ok := files[path.Clean(script.File)]
+		if !ok {
+			return nil, fmt.Errorf("tar bundle is missing %s for script '%s'", script.File, script.Name)
+		}
+		script.Command = string(body)
+		script.File = ""
+	}
+	return &bundle, nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+const (
+	lockFile    = "scripts.lock"
+	lockTimeout = 10 * time.Second
+	lockRetry   = 50 * time.Millisecond
+)
+
+type FileLock struct {
+	file *os.File
+}
+
+func GetLockPath() (string, error) {
+	configPath, err := GetConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), lockFile), nil
+}
+
+// LockScripts takes the advisory lock that serializes script mutations across
+// scripto processes. Project files are covered by the same lock.
+func LockScripts() (*FileLock, error) {
+	path, err := GetLockPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lock path: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return &FileLock{file: file}, nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) || time.Now().After(deadline) {
+			file.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				return nil, fmt.Errorf("timed out waiting for another scripto process to finish saving (%s)", path)
+			}
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		time.Sleep(lockRetry)
+	}
+}
+
+func (l *FileLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	defer l.file.Close()
+	return syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	_ "embed"
 	"fmt"
@@ -11,15 +12,65 @@ import (
 //go:embed migrations/001_initial.sql
 var migration001 string
 
+//go:embed migrations/002_scripts.sql
+var migration002 string
+
+//go:embed migrations/003_script_versions.sql
+var migration003 string
+
+//go:embed migrations/004_script_revisions.sql
+var migration004 string
+
+//go:embed migrations/005_trash.sql
+var migration005 string
+
+//go:embed migrations/006_script_timestamps.sql
+var migration006 string
+
+//go:embed migrations/007_script_pins.sql
+var migration007 string
+
+//go:embed migrations/008_script_aliases.sql
+var migration008 string
+
+//go:embed migrations/009_scope_variables.sql
+var migration009 string
+
+//go:embed migrations/010_allowed_values_cache.sql
+var migration010 string
+
+//go:embed migrations/011_script_auto_quote.sql
+var migration011 string
+
 var migrations = []struct {
 	name string
 	sql  string
 }{
 	{"001_initial", migration001},
+	{"002_scripts", migration002},
+	{"003_script_versions", migration003},
+	{"004_script_revisions", migration004},
+	{"005_trash", migration005},
+	{"006_script_timestamps", migration006},
+	{"007_script_pins", migration007},
+	{"008_script_aliases", migration008},
+	{"009_scope_variables", migration009},
+	{"010_allowed_values_cache", migration010},
+	{"011_script_auto_quote", migration011},
 }
 
+// applyMigrations runs on a single connection so that PRAGMA statements inside
+// a migration (such as toggling foreign_keys for a table rebuild) take effect
+// for the statements that follow them.
 func applyMigrations(db *sql.DB) error {
-	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
 		name TEXT PRIMARY KEY,
 		applied_at INTEGER NOT NULL
 	)`)
@@ -27,19 +78,24 @@ func applyMigrations(db *sql.DB) error {
 		return fmt.Errorf("failed to create schema_migrations table: %w", err)
 	}
 
-	rows, err := db.Query("SELECT name FROM schema_migrations")
+	applied, err := appliedMigrations(ctx, conn)
 	if err != nil {
-		return fmt.Errorf("failed to query applied migrations: %w", err)
+		return err
+	}
+	if len(applied) == len(migrations) {
+		return nil
 	}
-	defer rows.Close()
 
-	applied := make(map[string]bool)
-	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err != nil {
-			return fmt.Errorf("failed to scan migration name: %w", err)
-		}
-		applied[name] = true
+	lock, err := LockScripts()
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Another process may have applied the pending migrations while we waited.
+	applied, err = appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
 	}
 
 	sorted := make([]struct{ name, sql string }, len(migrations))
@@ -52,20 +108,82 @@ func applyMigrations(db *sql.DB) error {
 		if applied[m.name] {
 			continue
 		}
-		statements := strings.Split(m.sql, ";")
-		for _, stmt := range statements {
-			stmt = strings.TrimSpace(stmt)
-			if stmt == "" {
-				continue
-			}
-			if _, err := db.Exec(stmt); err != nil {
-				return fmt.Errorf("failed to apply migration %s: %w", m.name, err)
-			}
+		if err := applyMigration(ctx, conn, m.name, m.sql); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs the statements of one migration and records it in a
+// single transaction, so a failure leaves the schema as it was. PRAGMA
+// statements have no effect inside a transaction: the ones at the end of the
+// migration run after it, also when it fails, and the others before it.
+func applyMigration(ctx context.Context, conn *sql.Conn, name, migration string) error {
+	var before, body, after []string
+	for _, stmt := range strings.Split(migration, ";") {
+		stmt = strings.TrimSpace(stmt)
+		switch {
+		case stmt == "":
+		case strings.HasPrefix(strings.ToUpper(stmt), "PRAGMA"):
+			after = append(after, stmt)
+		default:
+			before = append(before, after...)
+			after = nil
+			body = append(body, stmt)
+		}
+	}
+
+	for _, stmt := range before {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", name, err)
 		}
-		if _, err := db.Exec("INSERT INTO schema_migrations (name, applied_at) VALUES (?, strftime('%s', 'now'))", m.name); err != nil {
-			return fmt.Errorf("failed to record migration %s: %w", m.name, err)
+	}
+	err := runMigrationTx(ctx, conn, name, body)
+	for _, stmt := range after {
+		if _, pragmaErr := conn.ExecContext(ctx, stmt); pragmaErr != nil && err == nil {
+			err = fmt.Errorf("failed to apply migration %s: %w", name, pragmaErr)
 		}
 	}
+	return err
+}
 
+func runMigrationTx(ctx context.Context, conn *sql.Conn, name string, statements []string) error {
+	if _, err := conn.ExecContext(ctx, "BEGIN"); err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", name, err)
+	}
+	for _, stmt := range statements {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			conn.ExecContext(ctx, "ROLLBACK")
+			return fmt.Errorf("failed to apply migration %s: %w", name, err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (name, applied_at) VALUES (?, strftime('%s', 'now'))", name); err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return fmt.Errorf("failed to record migration %s: %w", name, err)
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		conn.ExecContext(ctx, "ROLLBACK")
+		return fmt.Errorf("failed to commit migration %s: %w", name, err)
+	}
 	return nil
 }
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[string]bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan migration name: %w", err)
+		}
+		applied[name] = true
+	}
+	return applied, rows.Err()
+}
@@ -0,0 +1,62 @@
+CREATE TABLE IF NOT EXISTS meta (
+    key TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS scopes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS scripts (
+    id TEXT PRIMARY KEY,
+    scope_id INTEGER NOT NULL REFERENCES scopes(id),
+    name TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    file_path TEXT NOT NULL DEFAULT '',
+    archived INTEGER NOT NULL DEFAULT 0,
+    source TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_scripts_scope_id ON scripts(scope_id);
+CREATE INDEX IF NOT EXISTS idx_scripts_source ON scripts(source);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS script_tags (
+    script_id TEXT NOT NULL REFERENCES scripts(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (script_id, tag_id)
+);
+
+PRAGMA foreign_keys = OFF;
+
+CREATE TABLE execution_history_new (
+    id TEXT PRIMARY KEY,
+    execution_timestamp INTEGER NOT NULL,
+    script_id TEXT REFERENCES scripts(id) ON DELETE SET NULL,
+    executed_script TEXT NOT NULL,
+    original_script TEXT NOT NULL,
+    placeholder_values TEXT NOT NULL DEFAULT '{}',
+    working_directory TEXT NOT NULL DEFAULT '',
+    script_object_definition TEXT NOT NULL DEFAULT '{}',
+    executed_script_hash TEXT NOT NULL DEFAULT '',
+    original_script_hash TEXT NOT NULL DEFAULT ''
+);
+
+INSERT INTO execution_history_new
+SELECT id, execution_timestamp, NULLIF(script_id, ''), executed_script, original_script, placeholder_values,
+       working_directory, script_object_definition, executed_script_hash, original_script_hash
+FROM execution_history;
+
+DROP TABLE execution_history;
+
+ALTER TABLE execution_history_new RENAME TO execution_history;
+
+CREATE INDEX IF NOT EXISTS idx_script_id ON execution_history(script_id);
+CREATE INDEX IF NOT EXISTS idx_execution_timestamp ON execution_history(execution_timestamp);
+
+PRAGMA foreign_keys = ON
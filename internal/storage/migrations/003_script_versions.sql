@@ -0,0 +1 @@
+ALTER TABLE scripts ADD COLUMN version INTEGER NOT NULL DEFAULT 1
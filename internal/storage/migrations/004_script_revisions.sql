@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS script_revisions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    script_id TEXT NOT NULL REFERENCES scripts(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    created_at INTEGER NOT NULL,
+    body TEXT NOT NULL,
+    body_hash TEXT NOT NULL,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    UNIQUE (script_id, revision)
+);
+
+CREATE INDEX IF NOT EXISTS idx_script_revisions_script_id ON script_revisions(script_id)
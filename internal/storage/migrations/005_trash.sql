@@ -0,0 +1,3 @@
+ALTER TABLE scripts ADD COLUMN deleted_at INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_scripts_deleted_at ON scripts(deleted_at)
@@ -0,0 +1,3 @@
+ALTER TABLE scripts ADD COLUMN created_at INTEGER;
+
+ALTER TABLE scripts ADD COLUMN updated_at INTEGER
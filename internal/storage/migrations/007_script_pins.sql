@@ -0,0 +1 @@
+ALTER TABLE scripts ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0
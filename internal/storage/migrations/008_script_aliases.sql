@@ -0,0 +1 @@
+ALTER TABLE scripts ADD COLUMN aliases TEXT NOT NULL DEFAULT '[]'
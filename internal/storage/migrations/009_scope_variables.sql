@@ -0,0 +1 @@
+ALTER TABLE scopes ADD COLUMN variables TEXT NOT NULL DEFAULT '{}'
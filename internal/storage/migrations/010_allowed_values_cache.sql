@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS allowed_values_cache (
+    command TEXT NOT NULL,
+    working_directory TEXT NOT NULL,
+    allowed_values TEXT NOT NULL,
+    fetched_at INTEGER NOT NULL,
+    PRIMARY KEY (command, working_directory)
+)
@@ -0,0 +1 @@
+ALTER TABLE scripts ADD COLUMN auto_quote INTEGER NOT NULL DEFAULT 0
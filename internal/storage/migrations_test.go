@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("SCRIPTO_CONFIG", filepath.Join(dir, "scripts.json"))
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(dir, "test.sqlite")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func countMigrations(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestApplyMigrations_FromFirstSchema(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, "CREATE TABLE schema_migrations (name TEXT PRIMARY KEY, applied_at INTEGER NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyMigration(ctx, conn, migrations[0].name, migrations[0].sql); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx,
+		`INSERT INTO execution_history (id, execution_timestamp, script_id, executed_script, original_script)
+		 VALUES ('h1', 1, '', 'ls', 'ls')`); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if err := applyMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	if count := countMigrations(t, db); count != len(migrations) {
+		t.Errorf("expected %d migrations recorded, got %d", len(migrations), count)
+	}
+	var scriptID sql.NullString
+	if err := db.QueryRow("SELECT script_id FROM execution_history WHERE id = 'h1'").Scan(&scriptID); err != nil {
+		t.Fatalf("expected history to survive the rebuild: %v", err)
+	}
+	if scriptID.Valid {
+		t.Errorf("expected an empty script id to become NULL, got %q", scriptID.String)
+	}
+
+	// Running again is a no-op.
+	if err := applyMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyMigration_RollsBackOnFailure(t *testing.T) {
+	db := openTestDB(t)
+	if err := applyMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	before := countMigrations(t, db)
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	broken := `PRAGMA foreign_keys = OFF;
+CREATE TABLE half_done (id INTEGER);
+INSERT INTO no_such_table VALUES (1);
+PRAGMA foreign_keys = ON`
+	if err := applyMigration(ctx, conn, "999_broken", broken); err == nil {
+		t.Fatal("expected the migration to fail")
+	}
+
+	var tables int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'half_done'").Scan(&tables); err != nil {
+		t.Fatal(err)
+	}
+	if tables != 0 {
+		t.Error("expected the statements before the failure to be rolled back")
+	}
+	if count := countMigrations(t, db); count != before {
+		t.Errorf("expected no migration to be recorded, got %d rows instead of %d", count, before)
+	}
+	var foreignKeys int
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		t.Fatal(err)
+	}
+	if foreignKeys != 1 {
+		t.Error("expected the trailing PRAGMA to run after the failure")
+	}
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/vsuhanov/scripto/entities"
+)
+
+// ProjectConfig is the on-disk layout of a project-local .scripto/scripts.json.
+// Scripts in a project file always belong to the directory that contains the
+// .scripto folder, and their file paths are stored relative to that folder so
+// the file can be committed and shared.
+type ProjectConfig struct {
+	Scripts []*entities.Script `json:"scripts"`
+	Hash    string             `json:"-"`
+}
+
+type ProjectStore struct {
+	Root string
+}
+
+func NewProjectStore(root string) *ProjectStore {
+	return &ProjectStore{Root: root}
+}
+
+func ProjectStoreFromConfigPath(path string) *ProjectStore {
+	return &ProjectStore{Root: filepath.Dir(filepath.Dir(path))}
+}
+
+func (p *ProjectStore) Dir() string {
+	return filepath.Join(p.Root, configDir)
+}
+
+func (p *ProjectStore) ConfigPath() string {
+	return filepath.Join(p.Dir(), configFile)
+}
+
+func (p *ProjectStore) ScriptsDir() string {
+	return filepath.Join(p.Dir(), scriptsDir)
+}
+
+// FindProjectStores walks up from dir and returns every project store found,
+// nearest first. The personal config directory is never treated as a project.
+func FindProjectStores(dir string) ([]*ProjectStore, error) {
+	personalDir := ""
+	if configPath, err := GetConfigPath(); err == nil {
+		personalDir = filepath.Dir(configPath)
+	}
+	homeDir := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		homeDir = filepath.Join(home, configDir)
+	}
+
+	var stores []*ProjectStore
+	current := filepath.Clean(dir)
+	for {
+		candidate := filepath.Join(current, configDir)
+		if candidate != personalDir && candidate != homeDir {
+			if _, err := os.Stat(filepath.Join(candidate, configFile)); err == nil {
+				stores = append(stores, NewProjectStore(current))
+			} else if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to inspect project store %s: %w", candidate, err)
+			}
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+
+	return stores, nil
+}
+
+func ReadProjectConfig(store *ProjectStore) (*ProjectConfig, error) {
+	data, err := os.ReadFile(store.ConfigPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ProjectConfig{}, nil
+		}
+		return nil, err
+	}
+
+	config := ProjectConfig{Hash: hashBytes(data)}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", store.ConfigPath(), err)
+	}
+
+	for _, script := range config.Scripts {
+		script.Scope = store.Root
+		script.Source = store.ConfigPath()
+		if script.FilePath != "" && !filepath.IsAbs(script.FilePath) {
+			script.FilePath = filepath.Join(store.Dir(), script.FilePath)
+		}
+	}
+
+	return &config, nil
+}
+
+func WriteProjectConfig(store *ProjectStore, config *ProjectConfig) error {
+	onDisk := ProjectConfig{Scripts: make([]*entities.Script, 0, len(config.Scripts))}
+	for _, script := range config.Scripts {
+		if script.ID == "" {
+			script.ID = uuid.New().String()
+		}
+
+		copied := *script
+		copied.Scope = ""
+		if copied.FilePath != "" {
+			if rel, err := filepath.Rel(store.Dir(), copied.FilePath); err == nil && filepath.IsLocal(rel) {
+				copied.FilePath = filepath.ToSlash(rel)
+			}
+		}
+		onDisk.Scripts = append(onDisk.Scripts, &copied)
+	}
+
+	data, err := json.MarshalIndent(onDisk, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(store.ConfigPath(), data, 0644)
+}
+
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -13,6 +13,9 @@ func GetSQLitePath() (string, error) {
 	if customPath := os.Getenv("SCRIPTO_SQLITE_DB_PATH"); customPath != "" {
 		return customPath, nil
 	}
+	if customPath := os.Getenv("SCRIPTO_CONFIG"); customPath != "" {
+		return filepath.Join(filepath.Dir(customPath), "scripto.sqlite"), nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -30,7 +33,8 @@ func OpenSQLite() (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to create sqlite directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	dsn := "file:" + dbPath + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open sqlite: %w", err)
 	}
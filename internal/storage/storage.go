@@ -69,11 +69,7 @@ func WriteConfig(path string, config Config) error {
 		return err
 	}
 
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
-	}
-
-	return ioutil.WriteFile(path, data, 0644)
+	return WriteFileAtomic(path, data, 0644)
 }
 
 func GetShellExtension() string {
@@ -160,6 +156,10 @@ func SaveScriptToFile(name, command string) (string, error) {
 		return "", fmt.Errorf("failed to get scripts directory: %w", err)
 	}
 
+	return SaveScriptToDir(scriptsDir, name, command)
+}
+
+func SaveScriptToDir(scriptsDir, name, command string) (string, error) {
 	if err := os.MkdirAll(scriptsDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create scripts directory: %w", err)
 	}
@@ -167,7 +167,7 @@ func SaveScriptToFile(name, command string) (string, error) {
 	filename := GenerateScriptFilename(name, command)
 	filePath := filepath.Join(scriptsDir, filename)
 
-	if err := ioutil.WriteFile(filePath, []byte(command), 0644); err != nil {
+	if err := WriteFileAtomic(filePath, []byte(command), 0644); err != nil {
 		return "", fmt.Errorf("failed to write script file: %w", err)
 	}
 
@@ -270,11 +270,14 @@ func SyncShortcuts(config Config) error {
 
 	if globalScripts, exists := config["global"]; exists {
 		for _, script := range globalScripts {
-			if script.Name != "" {
-				shouldExist[script.Name] = true
-				
-				if err := CreateShortcutFunction(script.Name); err != nil {
-					return fmt.Errorf("failed to create shortcut for '%s': %w", script.Name, err)
+			for _, name := range append([]string{script.Name}, script.Aliases...) {
+				if name == "" {
+					continue
+				}
+				shouldExist[SanitizeForFilename(name)] = true
+
+				if err := CreateShortcutFunction(name); err != nil {
+					return fmt.Errorf("failed to create shortcut for '%s': %w", name, err)
 				}
 			}
 		}
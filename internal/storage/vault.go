@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	vaultFile       = "vault.json"
+	vaultVersion    = 1
+	vaultKDF        = "pbkdf2-sha256"
+	vaultIterations = 600000
+	vaultSaltSize   = 16
+)
+
+// ErrWrongPassphrase is returned when the vault cannot be decrypted with the
+// given passphrase.
+var ErrWrongPassphrase = errors.New("wrong vault passphrase")
+
+// VaultSecret is a named value in the vault. Scope works like a script scope:
+// "global", a directory or a directory pattern.
+type VaultSecret struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+	Value string `json:"value"`
+}
+
+// vaultEnvelope is the vault file. Only Data is encrypted; byte slices are
+// stored as base64.
+type vaultEnvelope struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+func GetVaultPath() (string, error) {
+	if customPath := os.Getenv("SCRIPTO_CONFIG"); customPath != "" {
+		return filepath.Join(filepath.Dir(customPath), vaultFile), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, configDir, vaultFile), nil
+}
+
+// ReadVault decrypts the vault at path. A missing vault has no secrets.
+func ReadVault(path, passphrase string) ([]*VaultSecret, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+
+	var envelope vaultEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse vault: %w", err)
+	}
+	if envelope.Version > vaultVersion {
+		return nil, fmt.Errorf("vault version %d is newer than supported version %d", envelope.Version, vaultVersion)
+	}
+	if envelope.KDF != vaultKDF {
+		return nil, fmt.Errorf("unsupported vault key derivation '%s'", envelope.KDF)
+	}
+
+	gcm, err := vaultCipher(passphrase, envelope.Salt, envelope.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("failed to parse vault: invalid nonce")
+	}
+	plaintext, err := gcm.Open(nil, envelope.Nonce, envelope.Data, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	var secrets []*VaultSecret
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse vault contents: %w", err)
+	}
+	return secrets, nil
+}
+
+// WriteVault encrypts secrets with a key derived from passphrase and a fresh
+// salt and writes them to path, readable only by the owner.
+func WriteVault(path, passphrase string, secrets []*VaultSecret) error {
+	if secrets == nil {
+		secrets = []*VaultSecret{}
+	}
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %w", err)
+	}
+
+	salt := make([]byte, vaultSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	gcm, err := vaultCipher(passphrase, salt, vaultIterations)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	data, err := json.MarshalIndent(vaultEnvelope{
+		Version:    vaultVersion,
+		KDF:        vaultKDF,
+		Iterations: vaultIterations,
+		Salt:       salt,
+		Nonce:      nonce,
+		Data:       gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %w", err)
+	}
+	if err := WriteFileAtomic(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	return nil
+}
+
+func vaultCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations <= 0 || len(salt) == 0 {
+		return nil, fmt.Errorf("failed to parse vault: missing key parameters")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteVault_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), vaultFile)
+	secrets := []*VaultSecret{
+		{Name: "TOKEN", Scope: "global", Value: "s3cret"},
+		{Name: "TOKEN", Scope: "/srv/app", Value: "app-token"},
+	}
+
+	if err := WriteVault(path, "correct horse", secrets); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected mode 0600, got %o", perm)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("expected the vault file not to contain a secret in the clear")
+	}
+
+	read, err := ReadVault(path, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(secrets) {
+		t.Fatalf("expected %d secrets, got %d", len(secrets), len(read))
+	}
+	for i, secret := range secrets {
+		if *read[i] != *secret {
+			t.Errorf("secret %d: expected %+v, got %+v", i, *secret, *read[i])
+		}
+	}
+}
+
+func TestReadVault_WrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), vaultFile)
+	if err := WriteVault(path, "correct horse", []*VaultSecret{{Name: "TOKEN", Scope: "global", Value: "s3cret"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	secrets, err := ReadVault(path, "battery staple")
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+	if secrets != nil {
+		t.Errorf("expected no secrets, got %v", secrets)
+	}
+}
+
+func TestReadVault_Missing(t *testing.T) {
+	secrets, err := ReadVault(filepath.Join(t.TempDir(), vaultFile), "anything")
+	if err != nil || secrets != nil {
+		t.Errorf("expected a missing vault to have no secrets, got %v, %v", secrets, err)
+	}
+}
@@ -0,0 +1,128 @@
+package templatex
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// The helpers below take the piped value as their last argument, so both
+// {{ upper .Name }} and {{ .Name | replace "-" "_" | upper }} work. Values are
+// converted to strings first, which lets them take bool placeholders too.
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+func titleCase(v interface{}) string {
+	runes := []rune(toString(v))
+	start := true
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			start = true
+		} else if start {
+			runes[i] = unicode.ToUpper(r)
+			start = false
+		}
+	}
+	return string(runes)
+}
+
+func split(sep string, v interface{}) []string {
+	s := toString(v)
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, sep)
+}
+
+func join(sep string, v interface{}) string {
+	switch list := v.(type) {
+	case []string:
+		return strings.Join(list, sep)
+	case []interface{}:
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = toString(item)
+		}
+		return strings.Join(parts, sep)
+	}
+	return toString(v)
+}
+
+// defaultIfEmpty returns def when v is empty; unlike the defaultValue
+// annotation it changes what renders, not what the form starts with.
+func defaultIfEmpty(def, v interface{}) interface{} {
+	if toString(v) == "" {
+		return def
+	}
+	return v
+}
+
+func now(layout string) string {
+	return time.Now().Format(layout)
+}
+
+func base64Decode(v interface{}) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(toString(v))
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func sha256Sum(v interface{}) string {
+	sum := sha256.Sum256([]byte(toString(v)))
+	return hex.EncodeToString(sum[:])
+}
+
+func pathJoin(elems ...interface{}) string {
+	parts := make([]string, len(elems))
+	for i, elem := range elems {
+		parts[i] = toString(elem)
+	}
+	return filepath.Join(parts...)
+}
+
+var helperFuncs = map[string]interface{}{
+	"upper":      func(v interface{}) string { return strings.ToUpper(toString(v)) },
+	"lower":      func(v interface{}) string { return strings.ToLower(toString(v)) },
+	"title":      titleCase,
+	"trim":       func(v interface{}) string { return strings.TrimSpace(toString(v)) },
+	"trimPrefix": func(prefix string, v interface{}) string { return strings.TrimPrefix(toString(v), prefix) },
+	"trimSuffix": func(suffix string, v interface{}) string { return strings.TrimSuffix(toString(v), suffix) },
+	"replace":    func(old, new string, v interface{}) string { return strings.ReplaceAll(toString(v), old, new) },
+	"split":      split,
+	"join":       join,
+	"default":    defaultIfEmpty,
+	"env":        os.Getenv,
+	"now":        now,
+	"b64enc":     func(v interface{}) string { return base64.StdEncoding.EncodeToString([]byte(toString(v))) },
+	"b64dec":     base64Decode,
+	"sha256":     sha256Sum,
+	"base":       func(v interface{}) string { return filepath.Base(toString(v)) },
+	"dir":        func(v interface{}) string { return filepath.Dir(toString(v)) },
+	"pathJoin":   pathJoin,
+	"uuid":       func() string { return uuid.NewString() },
+}
+
+func init() {
+	for name, fn := range helperFuncs {
+		execFuncMap[name] = fn
+		parseFuncMap[name] = func(...interface{}) interface{} { return nil }
+	}
+}
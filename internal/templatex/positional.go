@@ -0,0 +1,66 @@
+package templatex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PositionalOrder returns metas in the order positional arguments fill them:
+// a variable annotated with position N takes the Nth place and the others
+// take the free places in order of appearance.
+func PositionalOrder(metas []VariableMeta) ([]VariableMeta, error) {
+	byPosition := make(map[int]VariableMeta)
+	var rest []VariableMeta
+	last := 0
+	for _, meta := range metas {
+		if meta.Position == 0 {
+			rest = append(rest, meta)
+			continue
+		}
+		if other, ok := byPosition[meta.Position]; ok {
+			return nil, fmt.Errorf("position %d is given to both %s and %s", meta.Position, other.Name, meta.Name)
+		}
+		byPosition[meta.Position] = meta
+		last = max(last, meta.Position)
+	}
+
+	ordered := make([]VariableMeta, 0, len(metas))
+	for position := 1; position <= last || len(rest) > 0; position++ {
+		if meta, ok := byPosition[position]; ok {
+			ordered = append(ordered, meta)
+		} else if len(rest) > 0 {
+			ordered = append(ordered, rest[0])
+			rest = rest[1:]
+		}
+	}
+	return ordered, nil
+}
+
+// AssignPositional fills the placeholders missing from values with the
+// positional arguments, in PositionalOrder. More arguments than there are such
+// placeholders is an error.
+func AssignPositional(metas []VariableMeta, values map[string]string, positional []string) error {
+	if len(positional) == 0 {
+		return nil
+	}
+	ordered, err := PositionalOrder(metas)
+	if err != nil {
+		return err
+	}
+	var free []string
+	for _, meta := range ordered {
+		if _, ok := values[meta.Name]; !ok {
+			free = append(free, meta.Name)
+		}
+	}
+	if len(positional) > len(free) {
+		if len(free) == 0 {
+			return fmt.Errorf("unexpected argument '%s': every placeholder is already given", positional[0])
+		}
+		return fmt.Errorf("too many arguments: expected at most %d (%s), got %d", len(free), strings.Join(free, ", "), len(positional))
+	}
+	for i, value := range positional {
+		values[free[i]] = value
+	}
+	return nil
+}
@@ -2,7 +2,11 @@ package templatex
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 	"text/template/parse"
@@ -13,19 +17,42 @@ type VariableMeta struct {
 	Label         string
 	DefaultValue  string
 	AllowedValues []string
+	// AllowedValuesFrom is a shell command whose output lines are the allowed
+	// values, for lists that change such as namespaces or branches.
+	AllowedValuesFrom string
+	// Secret variables are entered masked and their values are redacted
+	// wherever a command is shown or stored.
+	Secret bool
+	// Type is one of int, bool, path, dir, url or duration; see ValidateValue.
+	Type string
+	// Pattern is a regular expression the whole value has to match.
+	Pattern string
+	// Position is the 1-based positional argument that fills the variable,
+	// or 0 to take the next free one in order of appearance.
+	Position int
 }
 
+// RedactedValue stands in for the value of a secret variable.
+const RedactedValue = "*****"
+
 var parseFuncMap = map[string]interface{}{
-	"label":         func(string, interface{}) interface{} { return nil },
-	"defaultValue":  func(string, interface{}) interface{} { return nil },
-	"allowedValues": func(...interface{}) interface{} { return nil },
-	"param":         func(interface{}, interface{}) interface{} { return nil },
-	"eq":            func(interface{}, interface{}) bool { return false },
-	"ne":            func(interface{}, interface{}) bool { return false },
-	"lt":            func(interface{}, interface{}) bool { return false },
-	"le":            func(interface{}, interface{}) bool { return false },
-	"gt":            func(interface{}, interface{}) bool { return false },
-	"ge":            func(interface{}, interface{}) bool { return false },
+	"label":             func(string, interface{}) interface{} { return nil },
+	"defaultValue":      func(string, interface{}) interface{} { return nil },
+	"allowedValues":     func(...interface{}) interface{} { return nil },
+	"allowedValuesFrom": func(string, interface{}) interface{} { return nil },
+	"param":             func(interface{}, interface{}) interface{} { return nil },
+	"secret":            func(interface{}) interface{} { return nil },
+	"type":              func(string, interface{}) interface{} { return nil },
+	"pattern":           func(string, interface{}) interface{} { return nil },
+	"position":          func(int, interface{}) interface{} { return nil },
+	"quote":             func(interface{}) interface{} { return nil },
+	"raw":               func(interface{}) interface{} { return nil },
+	"eq":                func(interface{}, interface{}) bool { return false },
+	"ne":                func(interface{}, interface{}) bool { return false },
+	"lt":                func(interface{}, interface{}) bool { return false },
+	"le":                func(interface{}, interface{}) bool { return false },
+	"gt":                func(interface{}, interface{}) bool { return false },
+	"ge":                func(interface{}, interface{}) bool { return false },
 }
 
 var execFuncMap = template.FuncMap{
@@ -37,12 +64,78 @@ var execFuncMap = template.FuncMap{
 		}
 		return nil
 	},
-	"param": func(varVal, piped interface{}) interface{} { return piped },
+	"allowedValuesFrom": func(command string, v interface{}) interface{} { return v },
+	"param":             func(varVal, piped interface{}) interface{} { return piped },
+	"secret":            func(v interface{}) interface{} { return v },
+	"type":              func(typ string, v interface{}) interface{} { return v },
+	"pattern":           func(re string, v interface{}) interface{} { return v },
+	"position":          func(n int, v interface{}) interface{} { return v },
+	"quote":             func(v interface{}) string { return ShellQuote(fmt.Sprint(v)) },
+	"raw":               func(v interface{}) interface{} { return v },
+	// vaultSecret is what {{ secret "NAME" }} runs; see markVaultSecrets.
+	vaultSecretFunc: func(name string) string { return RedactedValue },
+}
+
+const vaultSecretFunc = "vaultSecret"
+
+// SecretLookup returns the vault value of the secret read by
+// {{ secret "NAME" }}.
+type SecretLookup func(name string) (string, error)
+
+// ContextVariable is the reserved field that holds the Context of a run, as
+// in {{ .Scripto.GitBranch }}. It is never a placeholder.
+const ContextVariable = "Scripto"
+
+// Context is what templates read from {{ .Scripto.* }} without asking for a
+// value.
+type Context struct {
+	Cwd        string
+	GitRoot    string
+	GitBranch  string
+	Hostname   string
+	User       string
+	Scope      string
+	ScriptName string
+}
+
+// UnknownContextFieldError reports a .Scripto field that Context does not
+// have, such as a misspelled {{ .Scripto.Brnach }}.
+type UnknownContextFieldError struct {
+	Field string
+}
+
+func (e *UnknownContextFieldError) Error() string {
+	return fmt.Sprintf("unknown field .%s.%s, valid fields are %s", ContextVariable, e.Field, strings.Join(ContextFields(), ", "))
+}
+
+// ContextFields returns the names of the fields of Context.
+func ContextFields() []string {
+	var fields []string
+	for _, field := range reflect.VisibleFields(reflect.TypeOf(Context{})) {
+		fields = append(fields, field.Name)
+	}
+	return fields
+}
+
+// CheckContextFields returns an *UnknownContextFieldError when templateStr
+// reads a .Scripto field that does not exist. Other parse errors are left to
+// ExtractVariables.
+func CheckContextFields(templateStr string) error {
+	_, err := extract(templateStr)
+	var unknown *UnknownContextFieldError
+	if errors.As(err, &unknown) {
+		return err
+	}
+	return nil
 }
 
 type extractor struct {
 	vars  map[string]*VariableMeta
 	order []string
+	// usesContext is set when the template reads a .Scripto field.
+	usesContext bool
+	// unknownField is the first .Scripto field Context does not have.
+	unknownField string
 }
 
 func newExtractor() *extractor {
@@ -50,6 +143,16 @@ func newExtractor() *extractor {
 }
 
 func (e *extractor) getOrCreate(name string) *VariableMeta {
+	if name == ContextVariable || strings.HasPrefix(name, ContextVariable+".") {
+		// Annotations on a context field go nowhere.
+		e.usesContext = true
+		if field, _, _ := strings.Cut(strings.TrimPrefix(name, ContextVariable+"."), "."); name != ContextVariable && e.unknownField == "" {
+			if _, ok := reflect.TypeOf(Context{}).FieldByName(field); !ok {
+				e.unknownField = field
+			}
+		}
+		return &VariableMeta{Name: name}
+	}
 	if _, ok := e.vars[name]; !ok {
 		e.vars[name] = &VariableMeta{Name: name, Label: name}
 		e.order = append(e.order, name)
@@ -72,24 +175,24 @@ func (e *extractor) walk(list *parse.ListNode) {
 	for _, node := range list.Nodes {
 		switch n := node.(type) {
 		case *parse.ActionNode:
-			e.extractFromPipe(n.Pipe, false)
+			e.extractFromPipe(n.Pipe)
 		case *parse.IfNode:
-			e.extractFromPipe(n.Pipe, true)
+			e.extractFromPipe(n.Pipe)
 			e.walk(n.List)
 			e.walk(n.ElseList)
 		case *parse.RangeNode:
-			e.extractFromPipe(n.Pipe, false)
+			e.extractFromPipe(n.Pipe)
 			e.walk(n.List)
 			e.walk(n.ElseList)
 		case *parse.WithNode:
-			e.extractFromPipe(n.Pipe, true)
+			e.extractFromPipe(n.Pipe)
 			e.walk(n.List)
 			e.walk(n.ElseList)
 		}
 	}
 }
 
-func (e *extractor) extractFromPipe(pipe *parse.PipeNode, isCondition bool) {
+func (e *extractor) extractFromPipe(pipe *parse.PipeNode) {
 	if pipe == nil || len(pipe.Cmds) == 0 {
 		return
 	}
@@ -100,21 +203,43 @@ func (e *extractor) extractFromPipe(pipe *parse.PipeNode, isCondition bool) {
 	if field, ok := cmd0.Args[0].(*parse.FieldNode); ok {
 		varName := strings.Join(field.Ident, ".")
 		meta := e.getOrCreate(varName)
+		e.extractArguments(pipe.Cmds[1:])
 		e.applyAnnotations(meta, pipe.Cmds[1:])
-	} else if _, ok := cmd0.Args[0].(*parse.IdentifierNode); ok && isCondition {
-		e.extractAnnotatedCondition(pipe.Cmds)
+	} else if _, ok := cmd0.Args[0].(*parse.IdentifierNode); ok {
+		e.extractAnnotatedCall(pipe.Cmds)
 	}
 }
 
-func (e *extractor) extractAnnotatedCondition(cmds []*parse.CommandNode) {
-	var condVars []string
-	for _, arg := range cmds[0].Args[1:] {
-		if field, ok := arg.(*parse.FieldNode); ok {
-			name := strings.Join(field.Ident, ".")
+// extractArguments creates the variables passed as arguments to the functions
+// of a pipeline, as in {{ .Name | default .Fallback }}.
+func (e *extractor) extractArguments(cmds []*parse.CommandNode) {
+	for _, cmd := range cmds {
+		if len(cmd.Args) == 0 {
+			continue
+		}
+		for _, name := range fieldNames(cmd.Args[1:]) {
 			e.getOrCreate(name)
-			condVars = append(condVars, name)
 		}
 	}
+}
+
+// extractAnnotatedCall handles a pipeline that starts with a function, such
+// as a condition {{ if eq .Env "prod" }} or a helper {{ upper .Name }}. Its
+// variable arguments become placeholders; other arguments are ignored.
+func (e *extractor) extractAnnotatedCall(cmds []*parse.CommandNode) {
+	condVars := fieldNames(cmds[0].Args[1:])
+	for _, name := range condVars {
+		e.getOrCreate(name)
+	}
+	if args := cmds[0].Args; len(args) == 3 {
+		// {{ default "x" .Name }} defaults .Name like {{ .Name | default "x" }}.
+		if ident, ok := args[0].(*parse.IdentifierNode); ok && ident.Ident == "default" {
+			if field, ok := args[2].(*parse.FieldNode); ok {
+				e.applyAnnotations(e.getOrCreate(strings.Join(field.Ident, ".")), []*parse.CommandNode{{NodeType: parse.NodeCommand, Args: args[:2]}})
+			}
+		}
+	}
+	e.extractArguments(cmds[1:])
 	if len(cmds) == 1 || len(condVars) == 0 {
 		return
 	}
@@ -173,6 +298,14 @@ func (e *extractor) applyAnnotations(meta *VariableMeta, cmds []*parse.CommandNo
 					meta.DefaultValue = s.Text
 				}
 			}
+		case "default":
+			// The default helper renders its value for an empty one, so the
+			// form starts with it unless defaultValue says otherwise.
+			if len(cmd.Args) > 1 && meta.DefaultValue == "" {
+				if s, ok := cmd.Args[1].(*parse.StringNode); ok {
+					meta.DefaultValue = s.Text
+				}
+			}
 		case "allowedValues":
 			meta.AllowedValues = nil
 			for _, arg := range cmd.Args[1:] {
@@ -180,32 +313,308 @@ func (e *extractor) applyAnnotations(meta *VariableMeta, cmds []*parse.CommandNo
 					meta.AllowedValues = append(meta.AllowedValues, s.Text)
 				}
 			}
+		case "allowedValuesFrom":
+			if len(cmd.Args) > 1 {
+				if s, ok := cmd.Args[1].(*parse.StringNode); ok {
+					meta.AllowedValuesFrom = s.Text
+				}
+			}
+		case "secret":
+			meta.Secret = true
+		case "type":
+			if len(cmd.Args) > 1 {
+				if s, ok := cmd.Args[1].(*parse.StringNode); ok {
+					meta.Type = strings.ToLower(s.Text)
+				}
+			}
+		case "pattern":
+			if len(cmd.Args) > 1 {
+				if s, ok := cmd.Args[1].(*parse.StringNode); ok {
+					meta.Pattern = s.Text
+				}
+			}
+		case "position":
+			if len(cmd.Args) > 1 {
+				if n, ok := cmd.Args[1].(*parse.NumberNode); ok && n.IsInt && n.Int64 > 0 {
+					meta.Position = int(n.Int64)
+				}
+			}
 		}
 	}
 }
 
+// fieldNames returns the variables among nodes, looking into parenthesized
+// pipelines such as (trim .Name).
+func fieldNames(nodes []parse.Node) []string {
+	var names []string
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *parse.FieldNode:
+			names = append(names, strings.Join(n.Ident, "."))
+		case *parse.PipeNode:
+			for _, cmd := range n.Cmds {
+				names = append(names, fieldNames(cmd.Args)...)
+			}
+		}
+	}
+	return names
+}
+
+// ExtractVariables returns the placeholders of templateStr in order of
+// appearance. Fields of .Scripto are left out.
 func ExtractVariables(templateStr string) ([]VariableMeta, error) {
+	e, err := extract(templateStr)
+	if err != nil {
+		return nil, err
+	}
+	return e.results(), nil
+}
+
+func extract(templateStr string) (*extractor, error) {
 	trees, err := parse.Parse("tmpl", templateStr, "", "", parseFuncMap)
 	if err != nil {
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
-	tree, ok := trees["tmpl"]
-	if !ok || tree == nil || tree.Root == nil {
-		return nil, nil
-	}
 	e := newExtractor()
-	e.walk(tree.Root)
-	return e.results(), nil
+	if tree, ok := trees["tmpl"]; ok && tree != nil && tree.Root != nil {
+		e.walk(tree.Root)
+	}
+	if e.unknownField != "" {
+		return nil, &UnknownContextFieldError{Field: e.unknownField}
+	}
+	return e, nil
 }
 
+// Options change how a template is rendered.
+type Options struct {
+	// Lookup resolves {{ secret "NAME" }}; without it vault secrets render as
+	// RedactedValue.
+	Lookup SecretLookup
+	// AutoQuote shell-quotes every placeholder printed by the template, except
+	// where it is piped to raw or quote.
+	AutoQuote bool
+	// Context fills {{ .Scripto.* }}; its fields render empty without it.
+	Context Context
+}
+
+// Execute renders templateStr with values. Secrets read from the vault render
+// as RedactedValue; use ExecuteWithSecrets to resolve them.
 func Execute(templateStr string, values map[string]string) (string, error) {
-	tmpl, err := template.New("tmpl").Option("missingkey=zero").Funcs(execFuncMap).Parse(templateStr)
+	return Render(templateStr, values, Options{})
+}
+
+// ExecuteWithSecrets renders templateStr with values, resolving
+// {{ secret "NAME" }} through lookup.
+func ExecuteWithSecrets(templateStr string, values map[string]string, lookup SecretLookup) (string, error) {
+	return Render(templateStr, values, Options{Lookup: lookup})
+}
+
+// Render renders templateStr with values as opts say.
+func Render(templateStr string, values map[string]string, opts Options) (string, error) {
+	tmpl := template.New("tmpl").Option("missingkey=zero").Funcs(execFuncMap)
+	if lookup := opts.Lookup; lookup != nil {
+		tmpl.Funcs(template.FuncMap{vaultSecretFunc: func(name string) (string, error) { return lookup(name) }})
+	}
+	tmpl, err := tmpl.Parse(templateStr)
 	if err != nil {
 		return "", fmt.Errorf("parse error: %w", err)
 	}
+	markVaultSecrets(tmpl.Tree.Root)
+	if opts.AutoQuote {
+		quotePlaceholders(tmpl.Tree, tmpl.Tree.Root)
+	}
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, values); err != nil {
+	if err := tmpl.Execute(&buf, templateData(templateStr, values, opts.Context)); err != nil {
 		return "", fmt.Errorf("execute error: %w", err)
 	}
 	return strings.TrimSpace(buf.String()), nil
 }
+
+// UsesVaultSecrets reports whether templateStr reads a secret from the vault.
+func UsesVaultSecrets(templateStr string) bool {
+	trees, err := parse.Parse("tmpl", templateStr, "", "", parseFuncMap)
+	if err != nil {
+		return false
+	}
+	uses := false
+	for _, tree := range trees {
+		if tree.Root != nil && markVaultSecrets(tree.Root) {
+			uses = true
+		}
+	}
+	return uses
+}
+
+// markVaultSecrets renames secret where it starts a pipeline, as in
+// {{ secret "NAME" }}, so it reads the vault rather than acting as the
+// annotation it is after a pipe, as in {{ .Token | secret }}. It reports
+// whether anything was renamed.
+func markVaultSecrets(node parse.Node) bool {
+	marked := false
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return false
+		}
+		for _, child := range n.Nodes {
+			marked = markVaultSecrets(child) || marked
+		}
+	case *parse.ActionNode:
+		marked = markVaultSecrets(n.Pipe)
+	case *parse.IfNode:
+		marked = markBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		marked = markBranch(&n.BranchNode)
+	case *parse.WithNode:
+		marked = markBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		marked = markVaultSecrets(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for i, cmd := range n.Cmds {
+			if i == 0 && len(cmd.Args) > 0 {
+				if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && (ident.Ident == "secret" || ident.Ident == vaultSecretFunc) {
+					ident.Ident = vaultSecretFunc
+					marked = true
+				}
+			}
+			for _, arg := range cmd.Args {
+				marked = markVaultSecrets(arg) || marked
+			}
+		}
+	}
+	return marked
+}
+
+// quotePlaceholders pipes every action that prints a placeholder, as in
+// {{ .Name }} or {{ upper .Name }}, to quote unless it already ends up in
+// quote or raw. Conditions and function calls that read no placeholder, such
+// as {{ now "2006-01-02" }}, are left alone.
+func quotePlaceholders(tree *parse.Tree, node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			quotePlaceholders(tree, child)
+		}
+	case *parse.ActionNode:
+		pipe := n.Pipe
+		if len(pipe.Decl) > 0 || len(pipe.Cmds) == 0 || len(pipe.Cmds[0].Args) == 0 {
+			return
+		}
+		switch first := pipe.Cmds[0].Args[0].(type) {
+		case *parse.FieldNode:
+		case *parse.IdentifierNode:
+			if first.Ident == vaultSecretFunc || len(fieldNames(pipe.Cmds[0].Args[1:])) == 0 {
+				return
+			}
+		default:
+			return
+		}
+		for _, cmd := range pipe.Cmds {
+			if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok && (ident.Ident == "quote" || ident.Ident == "raw") {
+				return
+			}
+		}
+		quote := parse.NewIdentifier("quote").SetTree(tree).SetPos(n.Pos)
+		pipe.Cmds = append(pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{quote}})
+	case *parse.IfNode:
+		quotePlaceholders(tree, n.List)
+		quotePlaceholders(tree, n.ElseList)
+	case *parse.RangeNode:
+		quotePlaceholders(tree, n.List)
+		quotePlaceholders(tree, n.ElseList)
+	case *parse.WithNode:
+		quotePlaceholders(tree, n.List)
+		quotePlaceholders(tree, n.ElseList)
+	}
+}
+
+// ShellQuote returns value quoted for a POSIX shell, leaving values made of
+// safe characters only as they are.
+func ShellQuote(value string) string {
+	if value != "" && safeShellValue.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+var safeShellValue = regexp.MustCompile(`^[a-zA-Z0-9_.,/:@%+=-]+$`)
+
+func markBranch(n *parse.BranchNode) bool {
+	marked := markVaultSecrets(n.Pipe)
+	marked = markVaultSecrets(n.List) || marked
+	return markVaultSecrets(n.ElseList) || marked
+}
+
+// SecretValues returns the non-empty values given for the secret variables
+// among metas, keyed by variable name.
+func SecretValues(metas []VariableMeta, values map[string]string) map[string]string {
+	secrets := make(map[string]string)
+	for _, meta := range metas {
+		if meta.Secret && values[meta.Name] != "" {
+			secrets[meta.Name] = values[meta.Name]
+		}
+	}
+	return secrets
+}
+
+// minRedactLength is the shortest secret Redact looks for. A shorter value,
+// such as 1 or true, would hide unrelated text as well; RenderRedacted hides
+// those where the template prints them.
+const minRedactLength = 6
+
+// Redact replaces every occurrence of a secret value in text, as it is or
+// shell-quoted, with RedactedValue. Values shorter than minRedactLength are
+// left alone. Longer values go first so that a secret containing another one
+// is hidden completely.
+func Redact(text string, secrets map[string]string) string {
+	values := make([]string, 0, len(secrets))
+	for _, value := range secrets {
+		if len(value) >= minRedactLength {
+			values = append(values, value)
+			if quoted := ShellQuote(value); quoted != value {
+				values = append(values, quoted)
+			}
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, RedactedValue)
+	}
+	return text
+}
+
+// RenderRedacted renders templateStr like Render, but with RedactedValue in
+// place of every secret placeholder and of every secret read from the vault,
+// so secrets are hidden where they are printed whatever their length.
+func RenderRedacted(templateStr string, values map[string]string, opts Options) (string, error) {
+	metas, err := ExtractVariables(templateStr)
+	if err != nil {
+		return "", err
+	}
+	redacted := RedactValues(values, SecretValues(metas, values))
+	opts.Lookup = nil
+	result, err := Render(templateStr, redacted, opts)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(result, ShellQuote(RedactedValue), RedactedValue), nil
+}
+
+// RedactValues returns a copy of values with the secret ones replaced by
+// RedactedValue.
+func RedactValues(values map[string]string, secrets map[string]string) map[string]string {
+	redacted := make(map[string]string, len(values))
+	for name, value := range values {
+		if _, ok := secrets[name]; ok {
+			value = RedactedValue
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
@@ -1,6 +1,10 @@
 package templatex
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -205,3 +209,374 @@ func TestExecute_InvalidTemplate(t *testing.T) {
 		t.Fatal("expected error for invalid template")
 	}
 }
+
+func TestExtractVariables_SecretAnnotation(t *testing.T) {
+	metas, err := ExtractVariables(`curl -H "Authorization: {{ .Token | label "API token" | secret }}" {{ .URL }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metas) != 2 {
+		t.Fatalf("expected 2 variables, got %d", len(metas))
+	}
+	if !metas[0].Secret || metas[0].Label != "API token" {
+		t.Errorf("expected secret Token labelled 'API token', got %+v", metas[0])
+	}
+	if metas[1].Secret {
+		t.Errorf("expected URL not to be secret")
+	}
+}
+
+func TestValidateValue_RelativeToDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(t.TempDir())
+
+	if err := ValidateValue(VariableMeta{Type: "dir"}, "build", dir); err != nil {
+		t.Errorf("expected build to be found in %s, got %v", dir, err)
+	}
+	if err := ValidateValue(VariableMeta{Type: "dir"}, "build", ""); err == nil {
+		t.Errorf("expected build not to be found in the current directory")
+	}
+	if err := ValidateValue(VariableMeta{Type: "path"}, filepath.Join(dir, "build"), "/nonexistent"); err != nil {
+		t.Errorf("expected an absolute path to ignore dir, got %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tmpl := `login -u {{ .User }} -p {{ .Pass | secret }}`
+	values := map[string]string{"User": "bob", "Pass": "hunter2"}
+	metas, err := ExtractVariables(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(tmpl, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := SecretValues(metas, values)
+	if got := Redact(result, secrets); got != "login -u bob -p *****" {
+		t.Errorf("expected redacted command, got %q", got)
+	}
+	redacted := RedactValues(values, secrets)
+	if redacted["Pass"] != RedactedValue || redacted["User"] != "bob" {
+		t.Errorf("expected only Pass to be redacted, got %v", redacted)
+	}
+}
+
+func TestExecuteWithSecrets_VaultFunction(t *testing.T) {
+	tmpl := `deploy --token {{ secret "DEPLOY_TOKEN" }} {{ .Env | secret }}{{ if secret "DEBUG" }} -v{{ end }}`
+	metas, err := ExtractVariables(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metas) != 1 || metas[0].Name != "Env" || !metas[0].Secret {
+		t.Fatalf("expected only the secret Env variable, got %+v", metas)
+	}
+	if !UsesVaultSecrets(tmpl) || UsesVaultSecrets(`echo {{ .Env | secret }}`) {
+		t.Errorf("expected only templates calling secret \"NAME\" to use the vault")
+	}
+
+	vault := map[string]string{"DEPLOY_TOKEN": "t0k3n", "DEBUG": "1"}
+	result, err := ExecuteWithSecrets(tmpl, map[string]string{"Env": "prod"}, func(name string) (string, error) {
+		return vault[name], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "deploy --token t0k3n prod -v" {
+		t.Errorf("expected vault values in the command, got %q", result)
+	}
+
+	preview, err := Execute(tmpl, map[string]string{"Env": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview != "deploy --token ***** prod -v" {
+		t.Errorf("expected vault values to be redacted, got %q", preview)
+	}
+}
+
+func TestExtractVariables_TypeAndPattern(t *testing.T) {
+	metas, err := ExtractVariables(`kubectl scale --replicas={{ .Replicas | type "int" }} deploy/{{ .Name | pattern "[a-z-]+" }}{{ if .Wait | type "bool" }} --wait{{ end }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metas) != 3 {
+		t.Fatalf("expected 3 variables, got %d", len(metas))
+	}
+	if metas[0].Type != "int" || metas[1].Pattern != "[a-z-]+" || metas[2].Type != "bool" {
+		t.Errorf("expected type and pattern annotations, got %+v", metas)
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		meta  VariableMeta
+		value string
+		valid bool
+	}{
+		{VariableMeta{Type: "int"}, "3", true},
+		{VariableMeta{Type: "int"}, "three", false},
+		{VariableMeta{Type: "bool"}, "yes", true},
+		{VariableMeta{Type: "bool"}, "maybe", false},
+		{VariableMeta{Type: "dir"}, dir, true},
+		{VariableMeta{Type: "path"}, dir + "/missing", false},
+		{VariableMeta{Type: "url"}, "https://example.com/x", true},
+		{VariableMeta{Type: "url"}, "example.com", false},
+		{VariableMeta{Type: "duration"}, "1h30m", true},
+		{VariableMeta{Type: "duration"}, "90", false},
+		{VariableMeta{Type: "color"}, "red", false},
+		{VariableMeta{Pattern: "[a-z]+"}, "api", true},
+		{VariableMeta{Pattern: "[a-z]+"}, "api1", false},
+		{VariableMeta{Type: "int"}, "", true},
+	}
+	for _, tt := range tests {
+		err := ValidateValue(tt.meta, tt.value, "")
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateValue(%+v, %q) = %v, expected valid=%v", tt.meta, tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestExecute_BoolPlaceholder(t *testing.T) {
+	tmpl := `make {{ .Target }}{{ if .Verbose | type "bool" }} V=1{{ end }}`
+	for value, expected := range map[string]string{"true": "make all V=1", "false": "make all", "": "make all"} {
+		result, err := Execute(tmpl, map[string]string{"Target": "all", "Verbose": value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result != expected {
+			t.Errorf("Verbose=%q: expected %q, got %q", value, expected, result)
+		}
+	}
+}
+
+func TestExtractVariables_AllowedValuesFrom(t *testing.T) {
+	metas, err := ExtractVariables(`kubectl get pods -n {{ .Namespace | allowedValuesFrom "kubectl get ns -o name" | defaultValue "default" }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metas) != 1 || metas[0].AllowedValuesFrom != "kubectl get ns -o name" || metas[0].DefaultValue != "default" {
+		t.Fatalf("expected the allowedValuesFrom command, got %+v", metas)
+	}
+	result, err := Execute(`kubectl get pods -n {{ .Namespace | allowedValuesFrom "kubectl get ns -o name" }}`, map[string]string{"Namespace": "prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "kubectl get pods -n prod" {
+		t.Errorf("expected the annotation to pass the value through, got %q", result)
+	}
+}
+
+func TestRender_AutoQuote(t *testing.T) {
+	tmpl := `grep {{ .Pattern }} {{ .File | raw }}{{ if .Dir }} -r {{ .Dir }}{{ end }} > {{ .Out | quote }}`
+	values := map[string]string{"Pattern": "it's a $HOME; rm", "File": "*.go", "Dir": "src", "Out": "out file"}
+
+	result, err := Render(tmpl, values, Options{AutoQuote: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `grep 'it'\''s a $HOME; rm' *.go -r src > 'out file'`; result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+
+	result, err = Execute(tmpl, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `grep it's a $HOME; rm *.go -r src > 'out file'`; result != expected {
+		t.Errorf("expected only quote to quote without auto-quote, got %q", result)
+	}
+}
+
+func TestRedact_QuotedSecret(t *testing.T) {
+	secrets := map[string]string{"Pass": "it's secret"}
+	if got := Redact(`login -p 'it'\''s secret'`, secrets); got != "login -p *****" {
+		t.Errorf("expected the quoted secret to be redacted, got %q", got)
+	}
+}
+
+func TestRedact_ShortSecret(t *testing.T) {
+	tmpl := `tail -n 10 app.log | grep {{ .Pin | secret }}`
+	values := map[string]string{"Pin": "1"}
+	metas, err := ExtractVariables(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Execute(tmpl, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Redact(result, SecretValues(metas, values)); got != result {
+		t.Errorf("expected a short secret to be left to RenderRedacted, got %q", got)
+	}
+	redacted, err := RenderRedacted(tmpl, values, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redacted != "tail -n 10 app.log | grep *****" {
+		t.Errorf("expected only the placeholder to be redacted, got %q", redacted)
+	}
+}
+
+func TestRenderRedacted(t *testing.T) {
+	tmpl := `login -u {{ .User }} -p {{ .Pass | secret }} --token {{ secret "TOKEN" }}`
+	values := map[string]string{"User": "bob", "Pass": "it's true"}
+	lookup := func(name string) (string, error) { return "from-vault", nil }
+	redacted, err := RenderRedacted(tmpl, values, Options{AutoQuote: true, Lookup: lookup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redacted != "login -u bob -p ***** --token *****" {
+		t.Errorf("expected the secrets to be redacted, got %q", redacted)
+	}
+}
+
+func TestAssignPositional(t *testing.T) {
+	metas, err := ExtractVariables(`scp {{ .File }} {{ .Server | position 1 }}:{{ .Dir }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metas[1].Position != 1 {
+		t.Fatalf("expected Server at position 1, got %+v", metas[1])
+	}
+
+	values := map[string]string{}
+	if err := AssignPositional(metas, values, []string{"prod", "app.zip"}); err != nil {
+		t.Fatal(err)
+	}
+	if values["Server"] != "prod" || values["File"] != "app.zip" || values["Dir"] != "" {
+		t.Errorf("expected Server and File to be filled in that order, got %v", values)
+	}
+
+	values = map[string]string{"Server": "prod"}
+	if err := AssignPositional(metas, values, []string{"app.zip", "/srv"}); err != nil {
+		t.Fatal(err)
+	}
+	if values["File"] != "app.zip" || values["Dir"] != "/srv" {
+		t.Errorf("expected named values to be skipped, got %v", values)
+	}
+
+	if err := AssignPositional(metas, map[string]string{}, []string{"a", "b", "c", "d"}); err == nil {
+		t.Error("expected surplus arguments to be rejected")
+	}
+}
+
+func TestExecute_HelperFuncs(t *testing.T) {
+	t.Setenv("SCRIPTO_TEST_REGION", "eu-west-1")
+	tmpl := `{{ .Name | trim | replace "-" "_" | upper }} {{ title .Name }} {{ .Tags | split "," | join " " }} ` +
+		`{{ .Missing | default "none" }} {{ env "SCRIPTO_TEST_REGION" }} {{ .Name | b64enc | b64dec }} ` +
+		`{{ base .File }} {{ dir .File }} {{ pathJoin .Dir "bin" }} {{ sha256 "abc" | trimSuffix "ad" | len }}`
+	values := map[string]string{"Name": "my-app", "Tags": "a,b,c", "File": "/srv/app/run.sh", "Dir": "/opt"}
+
+	result, err := Execute(tmpl, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "MY_APP My-app a b c none eu-west-1 my-app run.sh /srv/app /opt/bin 62"
+	if result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+
+	if result, err := Execute(`{{ now "2006" }}-{{ uuid | len }}`, nil); err != nil || len(result) != 7 || !strings.HasSuffix(result, "-36") {
+		t.Errorf("expected a year and a uuid length, got %q (%v)", result, err)
+	}
+}
+
+func TestExtractVariables_HelperArguments(t *testing.T) {
+	metas, err := ExtractVariables(`tar czf {{ pathJoin .Dir "out.tgz" | label "Output" }} {{ env "HOME" }} {{ .Src | default .Fallback }} {{ upper (trim .Tag) }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, meta := range metas {
+		names = append(names, meta.Name)
+	}
+	if strings.Join(names, ",") != "Dir,Src,Fallback,Tag" {
+		t.Fatalf("expected only field arguments to become placeholders, got %v", names)
+	}
+	if metas[0].Label != "Output" {
+		t.Errorf("expected the annotation to apply to Dir, got %+v", metas[0])
+	}
+
+	result, err := Render(`echo {{ upper .Msg }} {{ now "2006" | len }}`, map[string]string{"Msg": "hi there"}, Options{AutoQuote: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "echo 'HI THERE' 4" {
+		t.Errorf("expected helper calls on placeholders to be quoted, got %q", result)
+	}
+}
+
+func TestExtractVariables_DefaultHelper(t *testing.T) {
+	metas, err := ExtractVariables(`deploy {{ .Env | default "staging" }} {{ default "eu" .Region }} {{ .Tag | defaultValue "latest" | default "main" }} {{ .Src | default .Fallback }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := make(map[string]string)
+	for _, meta := range metas {
+		defaults[meta.Name] = meta.DefaultValue
+	}
+	want := map[string]string{"Env": "staging", "Region": "eu", "Tag": "latest", "Src": "", "Fallback": ""}
+	for name, value := range want {
+		if got, ok := defaults[name]; !ok || got != value {
+			t.Errorf("expected %s to default to %q, got %q", name, value, got)
+		}
+	}
+
+	result, err := Execute(`deploy {{ .Env | default "staging" }}`, map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "deploy staging" {
+		t.Errorf("expected an empty value to render the default, got %q", result)
+	}
+}
+
+func TestRender_Context(t *testing.T) {
+	tmpl := `git push origin {{ .Scripto.GitBranch }}{{ if eq .Scripto.User "root" }} --dry-run{{ end }} # {{ .Note | label "Note" }} from {{ .Scripto.Cwd }}`
+	metas, err := ExtractVariables(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metas) != 1 || metas[0].Name != "Note" {
+		t.Fatalf("expected .Scripto fields not to be placeholders, got %+v", metas)
+	}
+
+	ctx := Context{Cwd: "/work/my repo", GitBranch: "main", User: "root"}
+	result, err := Render(tmpl, map[string]string{"Note": "hi"}, Options{Context: ctx, AutoQuote: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "git push origin main --dry-run # hi from '/work/my repo'"; result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+
+	result, err = Execute(`echo {{ .Scripto.GitBranch }}.`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "echo ." {
+		t.Errorf("expected an empty context without one given, got %q", result)
+	}
+}
+
+func TestExtractVariables_UnknownContextField(t *testing.T) {
+	_, err := ExtractVariables(`git push origin {{ .Scripto.Brnach }}`)
+	var unknown *UnknownContextFieldError
+	if !errors.As(err, &unknown) || unknown.Field != "Brnach" {
+		t.Fatalf("expected an unknown field error for Brnach, got %v", err)
+	}
+	if want := "unknown field .Scripto.Brnach, valid fields are Cwd, GitRoot, GitBranch, Hostname, User, Scope, ScriptName"; err.Error() != want {
+		t.Errorf("expected %q, got %q", want, err.Error())
+	}
+	if err := CheckContextFields(`{{ if .Scripto }}{{ .Scripto.GitBranch | upper }}{{ end }} {{ .Name }}`); err != nil {
+		t.Errorf("expected known fields to pass, got %v", err)
+	}
+	if err := CheckContextFields(`{{ .Name `); err != nil {
+		t.Errorf("expected parse errors to be left to ExtractVariables, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package templatex
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidateValue checks value against the type and pattern annotations of
+// meta. An empty value is left to the caller, which treats it as missing.
+// Relative path and dir values are looked up in dir, the directory the
+// command runs in, or in the current directory when dir is empty.
+func ValidateValue(meta VariableMeta, value, dir string) error {
+	if value == "" {
+		return nil
+	}
+	switch meta.Type {
+	case "":
+	case "int":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("'%s' is not a whole number", value)
+		}
+	case "bool":
+		if _, err := ParseBool(value); err != nil {
+			return err
+		}
+	case "path":
+		if _, err := os.Stat(resolvePath(value, dir)); err != nil {
+			return fmt.Errorf("'%s' does not exist", value)
+		}
+	case "dir":
+		info, err := os.Stat(resolvePath(value, dir))
+		if err != nil {
+			return fmt.Errorf("'%s' does not exist", value)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("'%s' is not a directory", value)
+		}
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("'%s' is not a URL, e.g. https://example.com", value)
+		}
+	case "duration":
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("'%s' is not a duration, e.g. 30s or 1h30m", value)
+		}
+	default:
+		return fmt.Errorf("unknown type '%s', use int, bool, path, dir, url or duration", meta.Type)
+	}
+
+	if meta.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + meta.Pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", meta.Pattern, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("'%s' does not match %s", value, meta.Pattern)
+		}
+	}
+	return nil
+}
+
+// ParseBool reads the value of a bool placeholder, which also accepts yes and
+// no.
+func ParseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off", "":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("'%s' is not true or false", value)
+	}
+	return b, nil
+}
+
+// resolvePath expands a leading ~ in path and makes a relative path relative
+// to dir.
+func resolvePath(path, dir string) string {
+	path = expandHome(path)
+	if dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(expandHome(dir), path)
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// templateData is what a template is executed with. Values of bool
+// placeholders become real booleans so that {{ if .Flag }} is false for
+// "false", and ctx is added when the template reads .Scripto; otherwise
+// values are used as they are.
+func templateData(templateStr string, values map[string]string, ctx Context) interface{} {
+	e, err := extract(templateStr)
+	if err != nil {
+		return values
+	}
+	metas := e.results()
+	hasBool := false
+	for _, meta := range metas {
+		if meta.Type == "bool" {
+			hasBool = true
+			break
+		}
+	}
+	if !hasBool && !e.usesContext {
+		return values
+	}
+
+	// Every key is filled in, as a missing one renders as "<no value>" in
+	// an interface map.
+	data := make(map[string]interface{}, len(values)+len(metas)+1)
+	for _, meta := range metas {
+		data[meta.Name] = ""
+	}
+	for name, value := range values {
+		data[name] = value
+	}
+	for _, meta := range metas {
+		if meta.Type == "bool" {
+			b, _ := ParseBool(values[meta.Name])
+			data[meta.Name] = b
+		}
+	}
+	if e.usesContext {
+		data[ContextVariable] = ctx
+	}
+	return data
+}
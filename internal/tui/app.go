@@ -28,7 +28,7 @@ func RunApp(container *services.Container, request TuiRequest) error {
 			record := m.GetPendingHistoryRecord()
 			log.Printf("RunApp: pendingHistoryRecord=%v, ExecutionHistoryService=%v", record != nil, container.ExecutionHistoryService != nil)
 			if record != nil && container.ExecutionHistoryService != nil {
-				log.Printf("RunApp: saving execution record scriptID=%q executedScript=%q", record.ScriptID, record.ExecutedScript)
+				log.Printf("RunApp: saving execution record scriptID=%q", record.ScriptID)
 				container.ExecutionHistoryService.SaveExecution(*record)
 			}
 			container.TerminalService.ExecuteCommand(cmd)
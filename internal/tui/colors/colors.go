@@ -125,4 +125,5 @@ var DangerButtonForeground = lipgloss.CompleteAdaptiveColor{
 var ScopeLocal = Success
 var ScopeParent = Warning
 var ScopeGlobal = Primary
+var ScopeMarker = Accent
 var ScopeOther = MutedText
@@ -218,6 +218,14 @@ func (s *ExecutionHistoryScreen) showPlaceholderForm(record services.ExecutionRe
 
 func (s *ExecutionHistoryScreen) reExecute(record services.ExecutionRecord) tea.Cmd {
 	return func() tea.Msg {
+		if services.HasSecretPlaceholders(record.OriginalScript) {
+			// The stored command has its secrets redacted, so ask for them again.
+			var script entities.Script
+			if record.ScriptObjectDefinition == "" || json.Unmarshal([]byte(record.ScriptObjectDefinition), &script) != nil {
+				return ErrorMsg(fmt.Errorf("this run used secret values that were not stored; run the script again instead"))
+			}
+			return ShowScriptExecutionWithWorkingDirMsg{script: &script}
+		}
 		cwd, _ := os.Getwd()
 		newRecord := services.ExecutionRecord{
 			ScriptID:               record.ScriptID,
@@ -228,7 +236,7 @@ func (s *ExecutionHistoryScreen) reExecute(record services.ExecutionRecord) tea.
 			ScriptObjectDefinition: record.ScriptObjectDefinition,
 		}
 		return ExecuteAppCommandMsg{
-			command:       s.container.TerminalService.PrepareScriptExecution(record.ExecutedScript, record.ScriptName, record.PlaceholderValues, record.WorkingDirectory, true),
+			command:       s.container.TerminalService.PrepareScriptExecution(record.ExecutedScript, "", record.ScriptName, record.PlaceholderValues, nil, record.WorkingDirectory, true),
 			historyRecord: &newRecord,
 		}
 	}
@@ -3,6 +3,7 @@ package tui
 import (
 	"log"
 	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/services"
 	"github.com/vsuhanov/scripto/internal/tui/colors"
 	"github.com/vsuhanov/scripto/internal/utils"
 	"strings"
@@ -41,7 +42,9 @@ func (m *MainListScreen) renderList(maxWidth, maxHeight int) string {
 	var items []string
 
 	for i, item := range listItems {
-		if item.script == nil {
+		if item.pinned {
+			items = append(items, formatPinnedHeader())
+		} else if item.script == nil {
 			var header string
 			if i == m.selectedItemIndex && item.isSelectableHeader() {
 				header = ListItemSelectedStyle.Width(maxListItemWidth - 4).Render(scopeHeaderRawText(item.scope))
@@ -129,6 +132,10 @@ func (m *MainListScreen) formatScriptItem(script entities.Script, index int, max
 	// return lipgloss.NewStyle().Width(maxWidth-4).Background(lipgloss.Color("#ff9900")).Render()
 }
 
+func formatPinnedHeader() string {
+	return lipgloss.NewStyle().Foreground(primaryColor).Bold(true).Render("★ Pinned")
+}
+
 func formatScopeHeader(scope string) string {
 	var header string
 	scopeType := getScopeType(scope)
@@ -141,6 +148,8 @@ func formatScopeHeader(scope string) string {
 		header = "◐ " + formatDirectoryName(scope)
 	case "global":
 		header = "○ Global Scripts"
+	case "marker":
+		header = "▣ " + formatDirectoryName(scope)
 	default:
 		header = formatDirectoryName(scope)
 	}
@@ -180,6 +189,8 @@ func scopeHeaderRawText(scope string) string {
 		return "● " + formatDirectoryName(scope)
 	case "parent":
 		return "◐ " + formatDirectoryName(scope)
+	case "marker":
+		return "▣ " + formatDirectoryName(scope)
 	case "other":
 		return "◌ " + formatDirectoryName(scope)
 	default:
@@ -195,6 +206,9 @@ func formatDirectoryName(dir string) string {
 	if dir == "global" {
 		return "Global Scripts"
 	}
+	if services.IsMarkerScope(dir) {
+		return "Projects with " + services.MarkerScopeFile(dir)
+	}
 
 	fullPath := dir
 
@@ -98,6 +98,10 @@ func (m *MainListScreen) formatPreviewMetadata(selected *entities.Script) string
 		dirLabel := "Directory"
 		if services.IsPatternScope(selected.Scope) {
 			dirLabel = "Pattern"
+		} else if services.IsGitScope(selected.Scope) {
+			dirLabel = "Repository"
+		} else if services.IsMarkerScope(selected.Scope) {
+			dirLabel = "Marker"
 		}
 		dirLine = fmt.Sprintf("%s: %s", dirLabel, dir)
 		if m.previewNavMode && m.previewFocusedElement == previewFocusDirectory {
@@ -113,6 +117,33 @@ func (m *MainListScreen) formatPreviewMetadata(selected *entities.Script) string
 		metadata = append(metadata, fmt.Sprintf("File: %s", filename))
 	}
 
+	if selected.Source != "" {
+		metadata = append(metadata, "Stored in: project file")
+	}
+
+	if selected.Pinned {
+		metadata = append(metadata, "Pinned: yes")
+	}
+
+	if selected.AutoQuote {
+		metadata = append(metadata, "Auto-quote: yes")
+	}
+
+	if len(selected.Aliases) > 0 {
+		metadata = append(metadata, "Aliases: "+strings.Join(selected.Aliases, ", "))
+	}
+
+	if len(selected.Tags) > 0 {
+		metadata = append(metadata, "Tags: #"+strings.Join(selected.Tags, " #"))
+	}
+
+	if !selected.CreatedAt.IsZero() {
+		metadata = append(metadata, fmt.Sprintf("Created: %s", selected.CreatedAt.Format(time.RFC822)))
+	}
+	if !selected.UpdatedAt.IsZero() && !selected.UpdatedAt.Equal(selected.CreatedAt) {
+		metadata = append(metadata, fmt.Sprintf("Updated: %s", selected.UpdatedAt.Format(time.RFC822)))
+	}
+
 	if selected.ID != "" && m.scriptStats != nil {
 		if stats, ok := m.scriptStats[selected.ID]; ok && stats.ExecutionCount > 0 {
 			lastRun := stats.LastExecutionTime.Format(time.RFC822)
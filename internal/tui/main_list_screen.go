@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -31,6 +32,10 @@ const (
 	sortLastExecution
 	sortFrequency
 	sortAlphabetic
+	sortNewest
+	sortOldest
+	sortRecentlyEdited
+	sortLeastRecentlyEdited
 	sortModeCount
 )
 
@@ -116,10 +121,17 @@ func (m *MainListScreen) activeScripts() []*entities.Script {
 type listItem struct {
 	script *entities.Script
 	scope  string
+	// pinned marks the header of the pinned section.
+	pinned bool
 }
 
 func (li listItem) isSelectableHeader() bool {
-	return li.script == nil && li.scope != "global"
+	return li.script == nil && li.scope != "global" && !li.pinned
+}
+
+// isSkippedHeader reports whether navigation should step over the item.
+func (li listItem) isSkippedHeader() bool {
+	return li.script == nil && !li.isSelectableHeader()
 }
 
 func (m *MainListScreen) buildListItems() []listItem {
@@ -127,10 +139,11 @@ func (m *MainListScreen) buildListItems() []listItem {
 	filtered := scripts
 	if m.searchMode {
 		if query := m.searchInput.Value(); query != "" {
-			if re, err := regexp.Compile("(?i)" + query); err == nil {
+			tags, pattern := parseSearchQuery(query)
+			if re, err := regexp.Compile("(?i)" + pattern); err == nil {
 				var matched []*entities.Script
 				for _, s := range scripts {
-					if re.MatchString(s.Name) {
+					if hasTagPrefixes(s, tags) && namesMatch(re, s) {
 						matched = append(matched, s)
 					}
 				}
@@ -139,8 +152,20 @@ func (m *MainListScreen) buildListItems() []listItem {
 		}
 	}
 	var items []listItem
-	var currentScope string
+	var unpinned []*entities.Script
 	for _, s := range filtered {
+		if !s.Pinned {
+			unpinned = append(unpinned, s)
+			continue
+		}
+		if len(items) == 0 {
+			items = append(items, listItem{pinned: true})
+		}
+		items = append(items, listItem{script: s})
+	}
+
+	var currentScope string
+	for _, s := range unpinned {
 		if s.Scope != currentScope {
 			items = append(items, listItem{scope: s.Scope})
 			currentScope = s.Scope
@@ -150,6 +175,49 @@ func (m *MainListScreen) buildListItems() []listItem {
 	return items
 }
 
+// parseSearchQuery splits '#tag' words off a search query. The remaining words
+// form the name pattern.
+func parseSearchQuery(query string) (tags []string, pattern string) {
+	var words []string
+	for _, word := range strings.Fields(query) {
+		if len(word) > 1 && strings.HasPrefix(word, "#") {
+			tags = append(tags, strings.ToLower(word[1:]))
+			continue
+		}
+		words = append(words, word)
+	}
+	return tags, strings.Join(words, " ")
+}
+
+// namesMatch reports whether re matches the name or one of the aliases of
+// script.
+func namesMatch(re *regexp.Regexp, script *entities.Script) bool {
+	for _, name := range services.ScriptNames(script) {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return re.MatchString(script.Name)
+}
+
+// hasTagPrefixes reports whether every prefix starts one of the script's tags,
+// so that the list narrows while a tag is still being typed.
+func hasTagPrefixes(script *entities.Script, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		found := false
+		for _, tag := range script.Tags {
+			if strings.HasPrefix(tag, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func (m *MainListScreen) SetStatusMessage(msg string) {
 	m.statusMsg = msg
 }
@@ -269,6 +337,14 @@ func (m *MainListScreen) sortScripts() {
 			return si.ExecutionCount > sj.ExecutionCount
 		case sortAlphabetic:
 			return i.Name < j.Name
+		case sortNewest:
+			return i.CreatedAt.After(j.CreatedAt)
+		case sortOldest:
+			return i.CreatedAt.Before(j.CreatedAt)
+		case sortRecentlyEdited:
+			return i.UpdatedAt.After(j.UpdatedAt)
+		case sortLeastRecentlyEdited:
+			return i.UpdatedAt.Before(j.UpdatedAt)
 		}
 		return false
 	}
@@ -362,21 +438,21 @@ func (m *MainListScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case ScriptDeletedMsg:
-		m.scripts = removeScript(m.scripts, msg.script)
-		m.allScripts = removeScript(m.allScripts, msg.script)
-		m.archivedScripts = removeScript(m.archivedScripts, msg.script)
+		m.scripts = removeScript(m.scripts, msg.scriptID)
+		m.allScripts = removeScript(m.allScripts, msg.scriptID)
+		m.archivedScripts = removeScript(m.archivedScripts, msg.scriptID)
 		if m.selectedItemIndex >= len(m.buildListItems()) && m.selectedItemIndex > 0 {
 			m.selectedItemIndex--
 		}
 		m.selectedScript = nil
 		m.previewViewport.SetContent("")
 		m.updateSelectedScript()
-		m.statusMsg = "Script deleted"
+		m.statusMsg = "Moved to trash (T to view)"
 		return m, nil
 
 	case ScriptArchivedMsg:
-		m.scripts = removeScript(m.scripts, msg.script)
-		m.allScripts = removeScript(m.allScripts, msg.script)
+		m.scripts = removeScript(m.scripts, msg.scriptID)
+		m.allScripts = removeScript(m.allScripts, msg.scriptID)
 		if m.selectedItemIndex >= len(m.buildListItems()) && m.selectedItemIndex > 0 {
 			m.selectedItemIndex--
 		}
@@ -393,9 +469,22 @@ func (m *MainListScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.loadScripts(),
 		)
 
+	case ScriptPinnedMsg:
+		status := "Unpinned"
+		if msg.pinned {
+			status = "Pinned"
+		}
+		return m, tea.Batch(
+			func() tea.Msg { return StatusMsg(status) },
+			m.loadScripts(),
+		)
+
 	case ErrorMsg:
 		m.err = error(msg)
 		m.ready = true
+		if errors.Is(m.err, services.ErrConflict) {
+			return m, m.loadScripts()
+		}
 		return m, nil
 
 	case StatusMsg:
@@ -556,7 +645,7 @@ func (m *MainListScreen) handleSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 		items := m.buildListItems()
 		if len(items) > 0 {
 			m.selectedItemIndex = (m.selectedItemIndex + 1) % len(items)
-			if items[m.selectedItemIndex].script == nil && items[m.selectedItemIndex].scope == "global" {
+			if items[m.selectedItemIndex].isSkippedHeader() {
 				m.selectedItemIndex = (m.selectedItemIndex + 1) % len(items)
 			}
 			m.updateSelectedScript()
@@ -566,7 +655,7 @@ func (m *MainListScreen) handleSearchInput(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 		items := m.buildListItems()
 		if len(items) > 0 {
 			m.selectedItemIndex = (m.selectedItemIndex - 1 + len(items)) % len(items)
-			if items[m.selectedItemIndex].script == nil && items[m.selectedItemIndex].scope == "global" {
+			if items[m.selectedItemIndex].isSkippedHeader() {
 				m.selectedItemIndex = (m.selectedItemIndex - 1 + len(items)) % len(items)
 			}
 			m.updateSelectedScript()
@@ -617,7 +706,7 @@ func (m *MainListScreen) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				items := m.buildListItems()
 				if len(items) > 0 {
 					m.selectedItemIndex = 0
-					if items[0].script == nil && items[0].scope == "global" && len(items) > 1 {
+					if items[0].isSkippedHeader() && len(items) > 1 {
 						m.selectedItemIndex = 1
 					}
 					m.updateSelectedScript()
@@ -665,6 +754,14 @@ func (m *MainListScreen) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, func() tea.Msg {
 			return ShowExecutionHistoryMsg{}
 		}
+	case "R":
+		if m.selectedScript != nil {
+			script := m.selectedScript
+			return m, func() tea.Msg {
+				return ShowRevisionsMsg{script: script}
+			}
+		}
+		return m, nil
 	case "?":
 		m.showHelp = !m.showHelp
 		return m, nil
@@ -688,6 +785,21 @@ func (m *MainListScreen) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			item := items[m.selectedItemIndex]
 			if item.isSelectableHeader() {
 				dir := item.scope
+				if services.IsGitScope(dir) {
+					cwd, _ := os.Getwd()
+					scope, root, ok := services.GitScopeForDir(cwd)
+					if !ok || scope != dir {
+						return m, nil
+					}
+					dir = root
+				} else if services.IsMarkerScope(dir) {
+					cwd, _ := os.Getwd()
+					root, ok := services.MarkerRootForDir(dir, cwd)
+					if !ok {
+						return m, nil
+					}
+					dir = root
+				}
 				return m, func() tea.Msg { return CdToDirectoryMsg{dir: dir} }
 			} else if item.script != nil {
 				script := item.script
@@ -705,14 +817,31 @@ func (m *MainListScreen) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleDeleteRequest()
 
 	case "D":
-		return m.handleImmediateDelete()
+		return m.handleTrashRequest()
+
+	case "T":
+		return m, func() tea.Msg { return ShowTrashMsg{} }
+
+	case "V":
+		scope := m.selectedScope()
+		return m, func() tea.Msg { return ShowScopeVariablesMsg{scope: scope} }
+
+	case "M":
+		return m, func() tea.Msg { return ShowScopesMsg{} }
+
+	case "p":
+		if m.selectedScript != nil {
+			id, pinned := m.selectedScript.ID, !m.selectedScript.Pinned
+			return m, func() tea.Msg { return PinScriptMsg{scriptID: id, pinned: pinned} }
+		}
+		return m, nil
 
 	case "j", "down":
 		if m.focusedPane == "list" {
 			items := m.buildListItems()
 			if len(items) > 0 {
 				m.selectedItemIndex = (m.selectedItemIndex + 1) % len(items)
-				if items[m.selectedItemIndex].script == nil && items[m.selectedItemIndex].scope == "global" {
+				if items[m.selectedItemIndex].isSkippedHeader() {
 					m.selectedItemIndex = (m.selectedItemIndex + 1) % len(items)
 				}
 				m.updateSelectedScript()
@@ -725,7 +854,7 @@ func (m *MainListScreen) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			items := m.buildListItems()
 			if len(items) > 0 {
 				m.selectedItemIndex = (m.selectedItemIndex - 1 + len(items)) % len(items)
-				if items[m.selectedItemIndex].script == nil && items[m.selectedItemIndex].scope == "global" {
+				if items[m.selectedItemIndex].isSkippedHeader() {
 					m.selectedItemIndex = (m.selectedItemIndex - 1 + len(items)) % len(items)
 				}
 				m.updateSelectedScript()
@@ -742,7 +871,7 @@ func (m *MainListScreen) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			items := m.buildListItems()
 			if len(items) > 0 {
 				m.selectedItemIndex = len(items) - 1
-				if items[m.selectedItemIndex].script == nil && items[m.selectedItemIndex].scope == "global" && m.selectedItemIndex > 0 {
+				if items[m.selectedItemIndex].isSkippedHeader() && m.selectedItemIndex > 0 {
 					m.selectedItemIndex--
 				}
 				m.updateSelectedScript()
@@ -822,10 +951,10 @@ func (m *MainListScreen) handleExternalEdit() (tea.Model, tea.Cmd) {
 	}
 }
 
-func removeScript(list []*entities.Script, target *entities.Script) []*entities.Script {
+func removeScript(list []*entities.Script, id string) []*entities.Script {
 	result := make([]*entities.Script, 0, len(list))
 	for _, s := range list {
-		if s != target {
+		if s.ID != id {
 			result = append(result, s)
 		}
 	}
@@ -838,32 +967,40 @@ func (m *MainListScreen) handleDeleteRequest() (tea.Model, tea.Cmd) {
 
 func (m *MainListScreen) handleImmediateDelete() (tea.Model, tea.Cmd) {
 	if m.selectedScript != nil {
-		script := m.selectedScript
-		if script.Archived {
+		id := m.selectedScript.ID
+		if m.selectedScript.Archived {
 			return m, func() tea.Msg {
-				return UnarchiveScriptMsg{script: script}
+				return UnarchiveScriptMsg{scriptID: id}
 			}
 		}
 		return m, func() tea.Msg {
-			return ArchiveScriptMsg{script: script}
+			return ArchiveScriptMsg{scriptID: id}
 		}
 	}
 	return m, nil
 }
 
+func (m *MainListScreen) handleTrashRequest() (tea.Model, tea.Cmd) {
+	if m.selectedScript != nil {
+		name := m.selectedScript.Name
+		if name == "" {
+			name = "Unnamed Script"
+		}
+		m.confirmDelete = true
+		m.statusMsg = fmt.Sprintf("Move '%s' to trash? (y/n)", name)
+	}
+	return m, nil
+}
+
 func (m *MainListScreen) handleDeleteConfirmation(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "y", "Y":
 		m.confirmDelete = false
+		m.statusMsg = ""
 		if m.selectedScript != nil {
-			script := m.selectedScript
-			if script.Archived {
-				return m, func() tea.Msg {
-					return UnarchiveScriptMsg{script: script}
-				}
-			}
+			id := m.selectedScript.ID
 			return m, func() tea.Msg {
-				return ArchiveScriptMsg{script: script}
+				return DeleteScriptMsg{scriptID: id}
 			}
 		}
 		return m, nil
@@ -927,7 +1064,7 @@ func (m *MainListScreen) renderFooter() string {
 	if m.confirmDelete {
 		keyHints = HelpStyle.Render("y/n: confirm/cancel")
 	} else {
-		keyHints = HelpStyle.Render("↵: execute • e: edit • E: external • d: delete • y: copy • tab: switch pane")
+		keyHints = HelpStyle.Render("↵: execute • e: edit • E: external • d: archive • D: trash • y: copy • tab: switch pane")
 	}
 
 	return FooterStyle.Width(m.width).Render(
@@ -949,17 +1086,25 @@ Navigation:
   g            Go to first script
   G            Go to last script
   tab          Switch between list and preview
+  /            Search by name; #tag words filter by tag
   
 Actions:
   ↵ (enter)    Execute selected script
   e            Edit script inline
   E            Edit script in external editor
-  d            Archive script (with confirmation) / Unarchive if archived
-  D            Archive script immediately / Unarchive if archived
+  d            Archive script / Unarchive if archived
+  D            Move script to trash (with confirmation)
   y            Copy command to clipboard
+  p            Pin / unpin script (pinned scripts are listed first)
+  R            Show revisions (diff and roll back)
 
 Other:
   S            Cycle scope view: current → all → all+archived
+  o / O        Cycle sort mode forward / backward (default, last run,
+               frequency, name, date added, recently edited)
+  T            Open trash (restore or purge deleted scripts)
+  V            Edit variables of the selected scope (prefill placeholders)
+  M            Manage scopes (move or merge scopes of moved projects)
   ?            Toggle this help
   q, Ctrl+C    Quit
 
@@ -978,6 +1123,34 @@ func sortModeName(mode int) string {
 		return "Sort: frequency"
 	case sortAlphabetic:
 		return "Sort: alphabetic"
+	case sortNewest:
+		return "Sort: date added (newest first)"
+	case sortOldest:
+		return "Sort: date added (oldest first)"
+	case sortRecentlyEdited:
+		return "Sort: recently edited"
+	case sortLeastRecentlyEdited:
+		return "Sort: least recently edited"
 	}
 	return ""
 }
+
+// selectedScope returns the scope of the selected header or script, or the
+// current directory when nothing is selected.
+func (m *MainListScreen) selectedScope() string {
+	items := m.buildListItems()
+	if m.selectedItemIndex < len(items) {
+		item := items[m.selectedItemIndex]
+		if item.script != nil {
+			if item.script.OriginalScope != "" {
+				return item.script.OriginalScope
+			}
+			return item.script.Scope
+		}
+		if item.scope != "" {
+			return item.scope
+		}
+	}
+	cwd, _ := os.Getwd()
+	return cwd
+}
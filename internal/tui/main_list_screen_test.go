@@ -170,3 +170,34 @@ func TestCalculateScrollWindowInvariants(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query         string
+		expectTags    []string
+		expectPattern string
+	}{
+		{query: "deploy", expectPattern: "deploy"},
+		{query: "#k8s", expectTags: []string{"k8s"}},
+		{query: "#K8s logs", expectTags: []string{"k8s"}, expectPattern: "logs"},
+		{query: "#db #release dump", expectTags: []string{"db", "release"}, expectPattern: "dump"},
+		{query: "# notes", expectPattern: "# notes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			tags, pattern := parseSearchQuery(tt.query)
+			if pattern != tt.expectPattern {
+				t.Errorf("pattern = %q, want %q", pattern, tt.expectPattern)
+			}
+			if len(tags) != len(tt.expectTags) {
+				t.Fatalf("tags = %v, want %v", tags, tt.expectTags)
+			}
+			for i := range tags {
+				if tags[i] != tt.expectTags[i] {
+					t.Errorf("tags = %v, want %v", tags, tt.expectTags)
+				}
+			}
+		})
+	}
+}
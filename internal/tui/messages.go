@@ -55,27 +55,37 @@ type EditScriptExternalMsg struct {
 }
 
 type DeleteScriptMsg struct {
-	script *entities.Script
+	scriptID string
 }
 
 type ScriptDeletedMsg struct {
-	script *entities.Script
+	scriptID string
 }
 
 type ArchiveScriptMsg struct {
-	script *entities.Script
+	scriptID string
 }
 
 type ScriptArchivedMsg struct {
-	script *entities.Script
+	scriptID string
 }
 
 type UnarchiveScriptMsg struct {
-	script *entities.Script
+	scriptID string
 }
 
 type ScriptUnarchivedMsg struct {
-	script *entities.Script
+	scriptID string
+}
+
+type PinScriptMsg struct {
+	scriptID string
+	pinned   bool
+}
+
+type ScriptPinnedMsg struct {
+	scriptID string
+	pinned   bool
 }
 
 type CopyScriptToClipboardMsg struct {
@@ -108,6 +118,28 @@ type ShowExecutionHistoryMsg struct {
 	scriptID string
 }
 
+type ShowRevisionsMsg struct {
+	script *entities.Script
+}
+
+type ShowTrashMsg struct{}
+
+type ShowScopeVariablesMsg struct {
+	scope string
+}
+
+type ShowScopesMsg struct{}
+
+// ShowVaultUnlockMsg asks for the vault passphrase and runs retry once the
+// vault is unlocked.
+type ShowVaultUnlockMsg struct {
+	retry tea.Cmd
+}
+
+type VaultUnlockedMsg struct {
+	retry tea.Cmd
+}
+
 type PendingExecutionHistoryRecord struct {
 	record services.ExecutionRecord
 }
@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -30,9 +31,20 @@ type fieldControl struct {
 	isSelect bool
 	input    textinput.Model
 	picker   list.Model
+	// isToggle fields hold a bool placeholder, switched with space.
+	isToggle bool
+	checked  bool
+	// loading is set while the allowedValuesFrom command runs; the field is
+	// a text input until its output turns it into a picker. loadErr keeps
+	// it a text input.
+	loading bool
+	loadErr string
 }
 
 func (f fieldControl) Value() string {
+	if f.isToggle {
+		return strconv.FormatBool(f.checked)
+	}
 	if f.isSelect {
 		if sel, ok := f.picker.SelectedItem().(selectItem); ok {
 			return sel.value
@@ -43,6 +55,10 @@ func (f fieldControl) Value() string {
 }
 
 func (f *fieldControl) SetValue(v string) {
+	if f.isToggle {
+		f.checked, _ = templatex.ParseBool(v)
+		return
+	}
 	if f.isSelect {
 		items := f.picker.Items()
 		for i, it := range items {
@@ -62,18 +78,23 @@ func (f *fieldControl) SetValue(v string) {
 }
 
 func (f *fieldControl) Focus() tea.Cmd {
-	if f.isSelect {
+	if f.isSelect || f.isToggle {
 		return nil
 	}
 	return f.input.Focus()
 }
 
 func (f *fieldControl) Blur() {
-	if !f.isSelect {
+	if !f.isSelect && !f.isToggle {
 		f.input.Blur()
 	}
 }
 
+// isText reports whether the field is edited by typing.
+func (f fieldControl) isText() bool {
+	return !f.isSelect && !f.isToggle
+}
+
 func buildSelectItems(allowedValues []string, defaultValue string) []list.Item {
 	items := make([]list.Item, 0, len(allowedValues))
 	found := false
@@ -102,7 +123,7 @@ func newSelectPicker(meta templatex.VariableMeta) list.Model {
 		Background(selectedBgColor).
 		BorderForeground(selectedBgColor)
 
-	picker := list.New(items, d, leftPaneWidth-6, len(items))
+	picker := list.New(items, d, leftPaneWidth-6, min(len(items), maxPickerHeight))
 	picker.SetShowTitle(false)
 	picker.SetShowFilter(false)
 	picker.SetShowStatusBar(false)
@@ -124,6 +145,8 @@ func newSelectPicker(meta templatex.VariableMeta) list.Model {
 type PlaceholderFormModel struct {
 	placeholders  []templatex.VariableMeta
 	fields        []fieldControl
+	// errors holds the validation error of each field, shown under it.
+	errors        []string
 	focused       int
 	submitted     bool
 	cancelled     bool
@@ -143,6 +166,9 @@ type PlaceholderFormModel struct {
 
 	showWorkingDir    bool
 	workingDirInput   textinput.Model
+	// allowedValuesDir is the working directory allowedValuesFrom commands
+	// were last run in.
+	allowedValuesDir  string
 	workingDirFocused bool
 	useCwdFocused     bool
 }
@@ -151,20 +177,38 @@ type placeholderHistoryLoadedMsg struct {
 	records []services.ExecutionRecord
 }
 
+type allowedValuesLoadedMsg struct {
+	index  int
+	dir    string
+	values []string
+	err    error
+}
+
 const leftPaneWidth = 54
 
+// maxPickerHeight keeps long allowed value lists from pushing the rest of the
+// form off screen.
+const maxPickerHeight = 10
+
 func NewPlaceholderForm(script *entities.Script, placeholders []templatex.VariableMeta,
 	width, height int, container *services.Container, originalScript string, workingDir string) PlaceholderFormModel {
 	fields := make([]fieldControl, len(placeholders))
 
 	for i, placeholder := range placeholders {
-		if len(placeholder.AllowedValues) > 0 {
+		if placeholder.Type == "bool" && !placeholder.Secret {
+			checked, _ := templatex.ParseBool(placeholder.DefaultValue)
+			fields[i] = fieldControl{isToggle: true, checked: checked}
+		} else if len(placeholder.AllowedValues) > 0 && placeholder.AllowedValuesFrom == "" && !placeholder.Secret {
 			fields[i] = fieldControl{isSelect: true, picker: newSelectPicker(placeholder)}
 		} else {
 			input := textinput.New()
-			input.Placeholder = placeholder.DefaultValue
 			input.Width = 50
-			fields[i] = fieldControl{isSelect: false, input: input}
+			if placeholder.Secret {
+				input.EchoMode = textinput.EchoPassword
+			} else {
+				input.Placeholder = placeholder.DefaultValue
+			}
+			fields[i] = fieldControl{isSelect: false, input: input, loading: placeholder.AllowedValuesFrom != "" && !placeholder.Secret}
 		}
 	}
 
@@ -197,6 +241,7 @@ func NewPlaceholderForm(script *entities.Script, placeholders []templatex.Variab
 	m := PlaceholderFormModel{
 		placeholders:      placeholders,
 		fields:            fields,
+		errors:            make([]string, len(fields)),
 		focused:           0,
 		values:            make(map[string]string),
 		buttonFocus:       0,
@@ -211,6 +256,7 @@ func NewPlaceholderForm(script *entities.Script, placeholders []templatex.Variab
 		showWorkingDir:    true,
 		workingDirInput:   wdInput,
 		workingDirFocused: workingDirFocused,
+		allowedValuesDir:  workingDir,
 	}
 
 	log.Printf("PlaceholderForm Init - Width: %d, Height: %d, ViewportWidth: %d, ViewportHeight: %d", width, height, vpWidth, vpHeight)
@@ -222,7 +268,8 @@ func (m PlaceholderFormModel) buildPreviewContent(values map[string]string) stri
 	if m.script == nil {
 		return ""
 	}
-	return args.NewArgumentProcessor(m.script).BuildPreviewCommand(values)
+	options := templatex.Options{AutoQuote: services.AutoQuote(m.script), Context: services.TemplateContext(m.script)}
+	return args.NewArgumentProcessor(m.script, options).BuildPreviewCommand(values)
 }
 
 func (m PlaceholderFormModel) currentValues() map[string]string {
@@ -233,8 +280,118 @@ func (m PlaceholderFormModel) currentValues() map[string]string {
 	return vals
 }
 
+// fieldValue returns the value a field submits, its default when left empty.
+func (m PlaceholderFormModel) fieldValue(i int) string {
+	value := m.fields[i].Value()
+	if value == "" {
+		value = m.placeholders[i].DefaultValue
+	}
+	return value
+}
+
+func (m *PlaceholderFormModel) validateField(i int) bool {
+	m.errors[i] = ""
+	if err := templatex.ValidateValue(m.placeholders[i], m.fieldValue(i), m.workingDirInput.Value()); err != nil {
+		m.errors[i] = err.Error()
+		return false
+	}
+	return true
+}
+
+// validateAll checks every field and returns the index of the first invalid
+// one, or -1.
+func (m *PlaceholderFormModel) validateAll() int {
+	first := -1
+	for i := range m.fields {
+		if !m.validateField(i) && first < 0 {
+			first = i
+		}
+	}
+	return first
+}
+
+// focusInvalid moves the focus to field i so its error can be fixed.
+func (m PlaceholderFormModel) focusInvalid(i int) (PlaceholderFormModel, tea.Cmd) {
+	m.historyFocused = false
+	m.workingDirFocused = false
+	m.useCwdFocused = false
+	m.workingDirInput.Blur()
+	m.fields[m.focused].Blur()
+	m.buttonFocus = 0
+	m.focused = i
+	return m, m.fields[i].Focus()
+}
+
 func (m PlaceholderFormModel) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, m.loadHistory())
+	return tea.Batch(textinput.Blink, m.loadHistory(), m.loadAllowedValues())
+}
+
+// loadAllowedValues runs the allowedValuesFrom commands in the background.
+func (m PlaceholderFormModel) loadAllowedValues() tea.Cmd {
+	if m.container == nil || m.container.AllowedValuesService == nil {
+		return nil
+	}
+	var cmds []tea.Cmd
+	for i, placeholder := range m.placeholders {
+		if placeholder.AllowedValuesFrom == "" || placeholder.Secret {
+			continue
+		}
+		i, command, dir := i, placeholder.AllowedValuesFrom, m.allowedValuesDir
+		cmds = append(cmds, func() tea.Msg {
+			values, err := m.container.AllowedValuesService.AllowedValues(command, dir)
+			return allowedValuesLoadedMsg{index: i, dir: dir, values: values, err: err}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// refreshAllowedValues runs the allowedValuesFrom commands again once the
+// working directory has been changed.
+func (m *PlaceholderFormModel) refreshAllowedValues() tea.Cmd {
+	dir := m.workingDirInput.Value()
+	if m.workingDirFocused || dir == m.allowedValuesDir {
+		return nil
+	}
+	m.allowedValuesDir = dir
+	for i, placeholder := range m.placeholders {
+		if placeholder.AllowedValuesFrom != "" && !placeholder.Secret {
+			m.fields[i].loading = true
+		}
+	}
+	return m.loadAllowedValues()
+}
+
+// applyAllowedValues turns a field into a picker of the loaded values, or back
+// into a text input when the command failed, keeping what was entered.
+func (m *PlaceholderFormModel) applyAllowedValues(msg allowedValuesLoadedMsg) tea.Cmd {
+	field := &m.fields[msg.index]
+	value := field.Value()
+	field.loading = false
+	field.loadErr = ""
+
+	if msg.err != nil {
+		log.Printf("allowedValuesFrom for %s: %v", m.placeholders[msg.index].Name, msg.err)
+		field.loadErr = msg.err.Error()
+		if !field.isSelect {
+			return nil
+		}
+		field.isSelect = false
+		field.input.SetValue(value)
+		if msg.index == m.focused && m.buttonFocus == 0 && !m.historyFocused && !m.workingDirFocused && !m.useCwdFocused {
+			return field.input.Focus()
+		}
+		return nil
+	}
+
+	meta := m.placeholders[msg.index]
+	meta.AllowedValues = msg.values
+	field.input.Blur()
+	field.isSelect = true
+	field.picker = newSelectPicker(meta)
+	if value != "" {
+		field.SetValue(value)
+	}
+	return nil
 }
 
 func (m PlaceholderFormModel) loadHistory() tea.Cmd {
@@ -377,6 +534,9 @@ func (m *PlaceholderFormModel) fillFromSelectedRow() {
 	}
 	r := m.historyRecords[cursor]
 	for i, p := range m.placeholders {
+		if p.Secret {
+			continue // only the redacted value was stored
+		}
 		m.fields[i].SetValue(r.PlaceholderValues[p.Name])
 	}
 	if m.showWorkingDir && r.WorkingDirectory != "" {
@@ -419,6 +579,14 @@ func (m PlaceholderFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case allowedValuesLoadedMsg:
+		if msg.dir != m.allowedValuesDir || msg.index >= len(m.fields) {
+			return m, nil
+		}
+		cmd := m.applyAllowedValues(msg)
+		m.viewport.SetContent(m.buildPreviewContent(m.currentValues()))
+		return m, cmd
+
 	case tea.KeyMsg:
 		if msg.String() == "ctrl+u" && m.showWorkingDir {
 			cwd, _ := os.Getwd()
@@ -431,7 +599,9 @@ func (m PlaceholderFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		if m.workingDirFocused {
-			return m.handleWorkingDirKey(msg)
+			m, cmd := m.handleWorkingDirKey(msg)
+			refresh := m.refreshAllowedValues()
+			return m, tea.Batch(cmd, refresh)
 		}
 
 		if m.useCwdFocused {
@@ -442,7 +612,7 @@ func (m PlaceholderFormModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 
 	if !m.historyFocused && !m.workingDirFocused && m.buttonFocus == 0 && len(m.fields) > 0 {
-		if !m.fields[m.focused].isSelect {
+		if m.fields[m.focused].isText() {
 			var cmd tea.Cmd
 			m.fields[m.focused].input, cmd = m.fields[m.focused].input.Update(msg)
 			m.viewport.SetContent(m.buildPreviewContent(m.currentValues()))
@@ -487,6 +657,10 @@ func (m PlaceholderFormModel) handleHistoryKey(msg tea.KeyMsg) (PlaceholderFormM
 
 	case "x":
 		m.fillFromSelectedRow()
+		if invalid := m.validateAll(); invalid >= 0 {
+			m.saveInputValues()
+			return m.focusInvalid(invalid)
+		}
 		values := m.currentValues()
 		for _, placeholder := range m.placeholders {
 			if values[placeholder.Name] == "" && placeholder.DefaultValue != "" {
@@ -584,6 +758,16 @@ func (m PlaceholderFormModel) handleUseCwdKey(msg tea.KeyMsg) (PlaceholderFormMo
 }
 
 func (m PlaceholderFormModel) handleFormKey(msg tea.KeyMsg) (PlaceholderFormModel, tea.Cmd) {
+	if m.buttonFocus == 0 && len(m.fields) > 0 && m.fields[m.focused].isToggle {
+		switch msg.String() {
+		case " ", "h", "l", "left", "right":
+			m.fields[m.focused].checked = !m.fields[m.focused].checked
+			m.validateField(m.focused)
+			m.viewport.SetContent(m.buildPreviewContent(m.currentValues()))
+			return m, nil
+		}
+	}
+
 	if m.buttonFocus == 0 && len(m.fields) > 0 && m.fields[m.focused].isSelect {
 		switch msg.String() {
 		case "j", "down", "l", "ctrl+n":
@@ -604,6 +788,13 @@ func (m PlaceholderFormModel) handleFormKey(msg tea.KeyMsg) (PlaceholderFormMode
 		}
 	}
 
+	switch msg.String() {
+	case "enter", "tab", "down", "shift+tab", "up":
+		if m.buttonFocus == 0 && len(m.fields) > 0 {
+			m.validateField(m.focused)
+		}
+	}
+
 	switch msg.String() {
 	case "ctrl+c", "esc":
 		m.cancelled = true
@@ -611,6 +802,9 @@ func (m PlaceholderFormModel) handleFormKey(msg tea.KeyMsg) (PlaceholderFormMode
 
 	case "enter":
 		if m.buttonFocus == 1 {
+			if invalid := m.validateAll(); invalid >= 0 {
+				return m.focusInvalid(invalid)
+			}
 			m.submitted = true
 			for i, placeholder := range m.placeholders {
 				value := m.fields[i].Value()
@@ -657,9 +851,12 @@ func (m PlaceholderFormModel) handleFormKey(msg tea.KeyMsg) (PlaceholderFormMode
 		return m.prevFocus()
 
 	default:
-		if m.buttonFocus == 0 && len(m.fields) > 0 && !m.fields[m.focused].isSelect {
+		if m.buttonFocus == 0 && len(m.fields) > 0 && m.fields[m.focused].isText() {
 			var cmd tea.Cmd
 			m.fields[m.focused].input, cmd = m.fields[m.focused].input.Update(msg)
+			if m.errors[m.focused] != "" {
+				m.validateField(m.focused)
+			}
 			m.viewport.SetContent(m.buildPreviewContent(m.currentValues()))
 			return m, cmd
 		}
@@ -760,10 +957,19 @@ func (m PlaceholderFormModel) View() string {
 	}
 
 	for i, placeholder := range m.placeholders {
+		field := m.fields[i]
+
 		b.WriteString(FieldLabelStyle.Render(placeholder.Label))
+		if hint := placeholderHint(placeholder); hint != "" {
+			b.WriteString(" ")
+			b.WriteString(DescriptionStyle.Render(hint))
+		}
+		if field.loading {
+			b.WriteString(" ")
+			b.WriteString(DescriptionStyle.Render("loading values…"))
+		}
 		b.WriteString("\n")
 
-		field := m.fields[i]
 		focused := i == m.focused && m.buttonFocus == 0 && !m.historyFocused && !m.workingDirFocused
 
 		fieldStyle := PlaceholderInputStyle
@@ -771,12 +977,26 @@ func (m PlaceholderFormModel) View() string {
 			fieldStyle = PlaceholderInputFocusedStyle
 		}
 
-		if field.isSelect {
+		switch {
+		case field.isToggle:
+			toggle := CheckboxStyle.Render("[ ] no")
+			if field.checked {
+				toggle = CheckboxCheckedStyle.Render("[x] yes")
+			}
+			b.WriteString(fieldStyle.Render(toggle))
+		case field.isSelect:
 			b.WriteString(fieldStyle.Render(field.picker.View()))
-		} else {
+		default:
 			b.WriteString(fieldStyle.Render(field.input.View()))
 		}
 		b.WriteString("\n")
+		if m.errors[i] != "" {
+			b.WriteString(ErrorStyle.Render(m.errors[i]))
+			b.WriteString("\n")
+		} else if field.loadErr != "" {
+			b.WriteString(DescriptionStyle.Render("Could not load values, type one: " + field.loadErr))
+			b.WriteString("\n")
+		}
 	}
 
 	b.WriteString("\n")
@@ -806,6 +1026,8 @@ func (m PlaceholderFormModel) View() string {
 		instructions = "Enter/Space: Set cwd • Tab: Next • Shift+Tab: Back • Esc: Cancel"
 	} else if m.buttonFocus == 0 && len(m.fields) > 0 && m.fields[m.focused].isSelect {
 		instructions = "j/k: Select • Tab: Next • Shift+Tab: Prev • Enter: Submit • Esc: Cancel"
+	} else if m.buttonFocus == 0 && len(m.fields) > 0 && m.fields[m.focused].isToggle {
+		instructions = "Space: Toggle • Tab: Next • Shift+Tab: Prev • Enter: Next • Esc: Cancel"
 	}
 	b.WriteString(InstructionStyle.Render(instructions))
 
@@ -838,3 +1060,18 @@ func (m PlaceholderFormModel) View() string {
 
 	return lipgloss.JoinVertical(lipgloss.Left, formPane, previewPane)
 }
+
+// placeholderHint describes the type and pattern a placeholder expects.
+func placeholderHint(meta templatex.VariableMeta) string {
+	var parts []string
+	if meta.Type != "" && meta.Type != "bool" {
+		parts = append(parts, meta.Type)
+	}
+	if meta.Pattern != "" {
+		parts = append(parts, meta.Pattern)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
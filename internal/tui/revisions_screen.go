@@ -0,0 +1,342 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/services"
+	. "github.com/vsuhanov/scripto/internal/utils"
+)
+
+type RevisionsScreen struct {
+	container *services.Container
+	script    *entities.Script
+	revisions []services.ScriptRevision
+	left      int
+	right     int
+	width     int
+	height    int
+	ready     bool
+	err       error
+	statusMsg string
+	table     table.Model
+	diffVP    viewport.Model
+
+	confirmRollback bool
+}
+
+type revisionsLoadedMsg struct {
+	revisions []services.ScriptRevision
+}
+
+type scriptRolledBackMsg struct {
+	script   *entities.Script
+	revision int
+}
+
+var (
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(errorColor)
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(successColor)
+	diffEqualStyle   = lipgloss.NewStyle().Foreground(textColor)
+)
+
+func NewRevisionsScreen(container *services.Container, script *entities.Script, width, height int) *RevisionsScreen {
+	return &RevisionsScreen{
+		container: container,
+		script:    script,
+		width:     width,
+		height:    height,
+		diffVP:    viewport.New(max(1, width-4), 1),
+	}
+}
+
+func (s *RevisionsScreen) calcHeights() (tableHeight, diffHeight int) {
+	available := s.height - 8
+	tableHeight = max(3, min(len(s.revisions)+1, available/3))
+	diffHeight = max(1, available-tableHeight-2)
+	return
+}
+
+func (s *RevisionsScreen) buildTable() table.Model {
+	tableH, _ := s.calcHeights()
+
+	const revWidth = 5
+	const tsWidth = 16
+	const hashWidth = 10
+	const markWidth = 4
+	nameWidth := max(10, s.width-4-revWidth-tsWidth-hashWidth-markWidth-10)
+
+	cols := []table.Column{
+		{Title: "Rev", Width: revWidth},
+		{Title: "Time", Width: tsWidth},
+		{Title: "Name", Width: nameWidth},
+		{Title: "Hash", Width: hashWidth},
+		{Title: "Diff", Width: markWidth},
+	}
+
+	rows := make([]table.Row, len(s.revisions))
+	for i, r := range s.revisions {
+		mark := ""
+		if i == s.left {
+			mark += "A"
+		}
+		if i == s.right {
+			mark += "B"
+		}
+		rows[i] = table.Row{
+			fmt.Sprintf("%d", r.Revision),
+			r.CreatedAt.Format("2006-01-02 15:04"),
+			TruncateString(r.Metadata.Name, nameWidth),
+			TruncateString(r.BodyHash, hashWidth-2),
+			mark,
+		}
+	}
+
+	tableStyle := table.DefaultStyles()
+	tableStyle.Header = tableStyle.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(borderColor).
+		BorderBottom(true).
+		Bold(true).
+		Foreground(primaryColor)
+	tableStyle.Selected = tableStyle.Selected.
+		Foreground(selectedTextColor).
+		Background(selectedBgColor).
+		Bold(true)
+
+	t := table.New(
+		table.WithColumns(cols),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(tableH),
+		table.WithStyles(tableStyle),
+	)
+	if s.table.Cursor() < len(rows) {
+		t.SetCursor(s.table.Cursor())
+	}
+	return t
+}
+
+func (s *RevisionsScreen) Init() tea.Cmd {
+	return s.loadRevisions()
+}
+
+func (s *RevisionsScreen) loadRevisions() tea.Cmd {
+	return func() tea.Msg {
+		revisions, err := s.container.ScriptService.GetRevisions(s.script.ID)
+		if err != nil {
+			return ErrorMsg(fmt.Errorf("failed to load revisions: %w", err))
+		}
+		return revisionsLoadedMsg{revisions: revisions}
+	}
+}
+
+func (s *RevisionsScreen) layout() {
+	_, diffH := s.calcHeights()
+	s.diffVP.Width = max(1, s.width-4)
+	s.diffVP.Height = diffH
+	s.table = s.buildTable()
+	s.updateDiff()
+}
+
+func (s *RevisionsScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.width = msg.Width
+		s.height = msg.Height
+		if s.ready {
+			s.layout()
+		}
+		return s, nil
+
+	case revisionsLoadedMsg:
+		s.revisions = msg.revisions
+		s.ready = true
+		s.right = 0
+		s.left = min(1, max(0, len(s.revisions)-1))
+		s.layout()
+		return s, nil
+
+	case scriptRolledBackMsg:
+		s.script = msg.script
+		s.statusMsg = fmt.Sprintf("Rolled back to revision %d", msg.revision)
+		return s, s.loadRevisions()
+
+	case ErrorMsg:
+		s.err = error(msg)
+		s.ready = true
+		return s, nil
+
+	case tea.KeyMsg:
+		return s.handleKey(msg)
+	}
+
+	var cmd tea.Cmd
+	s.diffVP, cmd = s.diffVP.Update(msg)
+	return s, cmd
+}
+
+func (s *RevisionsScreen) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if s.confirmRollback {
+		switch msg.String() {
+		case "y", "Y":
+			s.confirmRollback = false
+			return s, s.rollback()
+		case "n", "N", "esc":
+			s.confirmRollback = false
+			s.statusMsg = "Cancelled"
+		}
+		return s, nil
+	}
+
+	switch msg.String() {
+	case "q", "esc", "ctrl+c":
+		return s, func() tea.Msg { return NavigateBackMsg{} }
+
+	case "a":
+		if s.table.Cursor() < len(s.revisions) {
+			s.left = s.table.Cursor()
+			s.layout()
+		}
+		return s, nil
+
+	case "b":
+		if s.table.Cursor() < len(s.revisions) {
+			s.right = s.table.Cursor()
+			s.layout()
+		}
+		return s, nil
+
+	case "R":
+		if s.table.Cursor() < len(s.revisions) {
+			s.confirmRollback = true
+			s.statusMsg = ""
+		}
+		return s, nil
+
+	case "ctrl+d", "pgdown":
+		s.diffVP.HalfViewDown()
+		return s, nil
+
+	case "ctrl+u", "pgup":
+		s.diffVP.HalfViewUp()
+		return s, nil
+
+	default:
+		var cmd tea.Cmd
+		s.table, cmd = s.table.Update(msg)
+		return s, cmd
+	}
+}
+
+func (s *RevisionsScreen) rollback() tea.Cmd {
+	revision := s.revisions[s.table.Cursor()].Revision
+	script := s.script
+	return func() tea.Msg {
+		updated, err := s.container.ScriptService.RollbackScript(script, revision)
+		if err != nil {
+			return ErrorMsg(fmt.Errorf("failed to roll back: %w", err))
+		}
+		return scriptRolledBackMsg{script: updated, revision: revision}
+	}
+}
+
+func (s *RevisionsScreen) updateDiff() {
+	if len(s.revisions) == 0 {
+		s.diffVP.SetContent("")
+		return
+	}
+	left := s.revisions[s.left]
+	right := s.revisions[s.right]
+
+	colWidth := max(10, (s.diffVP.Width-3)/2)
+	rows := SideBySide(DiffLines(left.Body, right.Body))
+
+	var sb strings.Builder
+	sb.WriteString(s.renderDiffRow(
+		FieldLabelStyle.Render(fmt.Sprintf("A: revision %d", left.Revision)),
+		FieldLabelStyle.Render(fmt.Sprintf("B: revision %d", right.Revision)),
+		colWidth,
+	))
+	if left.Metadata.Name != right.Metadata.Name || left.Metadata.Description != right.Metadata.Description {
+		sb.WriteString("\n")
+		sb.WriteString(s.renderDiffRow(
+			diffRemovedStyle.Render(TruncateString(left.Metadata.Name+": "+left.Metadata.Description, colWidth)),
+			diffAddedStyle.Render(TruncateString(right.Metadata.Name+": "+right.Metadata.Description, colWidth)),
+			colWidth,
+		))
+	}
+	for _, row := range rows {
+		sb.WriteString("\n")
+		sb.WriteString(s.renderDiffRow(renderDiffCell(row.Left, colWidth), renderDiffCell(row.Right, colWidth), colWidth))
+	}
+
+	s.diffVP.SetContent(sb.String())
+	s.diffVP.GotoTop()
+}
+
+func (s *RevisionsScreen) renderDiffRow(left, right string, colWidth int) string {
+	cell := lipgloss.NewStyle().Width(colWidth).MaxWidth(colWidth)
+	return cell.Render(left) + " │ " + cell.Render(right)
+}
+
+func renderDiffCell(line *DiffLine, width int) string {
+	if line == nil {
+		return ""
+	}
+	text := TruncateString(strings.ReplaceAll(line.Text, "\t", "    "), width-2)
+	switch line.Op {
+	case DiffRemoved:
+		return diffRemovedStyle.Render("- " + text)
+	case DiffAdded:
+		return diffAddedStyle.Render("+ " + text)
+	default:
+		return diffEqualStyle.Render("  " + text)
+	}
+}
+
+func (s *RevisionsScreen) View() string {
+	if !s.ready {
+		return LoadingStyle.Render("Loading revisions...")
+	}
+
+	name := s.script.Name
+	if name == "" {
+		name = "Unnamed Script"
+	}
+	header := TitleStyle.Render(fmt.Sprintf("Revisions: %s", name))
+
+	if s.err != nil {
+		footer := HelpStyle.Render("q/esc: back")
+		return lipgloss.JoinVertical(lipgloss.Left, header, ErrorStyle.Render(fmt.Sprintf("Error: %v", s.err)), footer)
+	}
+
+	if len(s.revisions) == 0 {
+		body := NoScriptsStyle.Render("No revisions recorded for this script.")
+		footer := HelpStyle.Render("q/esc: back")
+		return lipgloss.JoinVertical(lipgloss.Left, header, body, footer)
+	}
+
+	tablePane := ListStyle.Width(s.width - 2).Render(s.table.View())
+	diffPane := PreviewStyle.Width(s.width - 2).Render(s.diffVP.View())
+
+	var footer string
+	if s.confirmRollback {
+		revision := s.revisions[s.table.Cursor()].Revision
+		footer = ErrorStyle.Render(fmt.Sprintf("Roll back to revision %d? (y/n)", revision))
+	} else {
+		help := "j/k: navigate • a: diff side A • b: diff side B • R: roll back to selected • ctrl+d/ctrl+u: scroll diff • q/esc: back"
+		if s.statusMsg != "" {
+			help = s.statusMsg + " • " + help
+		}
+		footer = HelpStyle.Render(help)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, tablePane, diffPane, footer)
+}
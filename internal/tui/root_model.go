@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -142,7 +143,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			realScope = msg.script.OriginalScope
 		}
 		scopeType := getScopeType(realScope)
-		if scopeType == "local" || scopeType == "global" {
+		if scopeType == "local" || scopeType == "marker" || scopeType == "global" {
 			return m, m.handleExecuteScript(msg.script, msg.scriptArgs, !msg.fromCLI)
 		}
 		return m, m.showExecutionForm(msg.script, msg.scriptArgs, msg.fromCLI)
@@ -188,13 +189,16 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.finalizeCopy(script, msg.values)
 
 	case DeleteScriptMsg:
-		return m, m.handleDeleteScript(msg.script)
+		return m, m.handleDeleteScript(msg.scriptID)
 
 	case ArchiveScriptMsg:
-		return m, m.handleArchiveScript(msg.script)
+		return m, m.handleArchiveScript(msg.scriptID)
 
 	case UnarchiveScriptMsg:
-		return m, m.handleUnarchiveScript(msg.script)
+		return m, m.handleUnarchiveScript(msg.scriptID)
+
+	case PinScriptMsg:
+		return m, m.handlePinScript(msg.scriptID, msg.pinned)
 
 	case EditScriptExternalMsg:
 		return m, m.handleEditScriptExternal(msg.script)
@@ -254,6 +258,43 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.currentScreen = execHistoryScreen
 		return m, execHistoryScreen.Init()
 
+	case ShowRevisionsMsg:
+		revisionsScreen := NewRevisionsScreen(m.container, msg.script, m.width, m.height)
+		m.screenStack = append(m.screenStack, m.currentScreen)
+		m.currentScreen = revisionsScreen
+		return m, revisionsScreen.Init()
+
+	case ShowTrashMsg:
+		trashScreen := NewTrashScreen(m.container, m.width, m.height)
+		m.screenStack = append(m.screenStack, m.currentScreen)
+		m.currentScreen = trashScreen
+		return m, trashScreen.Init()
+
+	case ShowScopeVariablesMsg:
+		scopeVarsScreen := NewScopeVariablesScreen(m.container, msg.scope, m.width, m.height)
+		m.screenStack = append(m.screenStack, m.currentScreen)
+		m.currentScreen = scopeVarsScreen
+		return m, scopeVarsScreen.Init()
+
+	case ShowScopesMsg:
+		scopesScreen := NewScopesScreen(m.container, m.width, m.height)
+		m.screenStack = append(m.screenStack, m.currentScreen)
+		m.currentScreen = scopesScreen
+		return m, scopesScreen.Init()
+
+	case ShowVaultUnlockMsg:
+		unlockScreen := NewVaultUnlockScreen(msg.retry, m.container, m.width, m.height)
+		m.screenStack = append(m.screenStack, m.currentScreen)
+		m.currentScreen = unlockScreen
+		return m, unlockScreen.Init()
+
+	case VaultUnlockedMsg:
+		if len(m.screenStack) > 0 {
+			m.currentScreen = m.screenStack[len(m.screenStack)-1]
+			m.screenStack = m.screenStack[:len(m.screenStack)-1]
+		}
+		return m, msg.retry
+
 	case NavigateBackMsg:
 		if len(m.screenStack) > 0 {
 			m.currentScreen = m.screenStack[len(m.screenStack)-1]
@@ -270,7 +311,7 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		dir := msg.dir
 		return m, func() tea.Msg {
 			return ExecuteAppCommandMsg{
-				command: m.container.TerminalService.PrepareScriptExecution("cd "+shellQuote(dir), "", nil, "", false),
+				command: m.container.TerminalService.PrepareScriptExecution("cd "+shellQuote(dir), "", "", nil, nil, "", false),
 			}
 		}
 
@@ -310,6 +351,18 @@ func scriptDefaultWorkingDir(script *entities.Script) string {
 	if services.IsPatternScope(script.Scope) {
 		return cwd
 	}
+	if services.IsGitScope(script.Scope) {
+		if scope, root, ok := services.GitScopeForDir(cwd); ok && scope == script.Scope {
+			return root
+		}
+		return cwd
+	}
+	if services.IsMarkerScope(script.Scope) {
+		if root, ok := services.MarkerRootForDir(script.Scope, cwd); ok {
+			return root
+		}
+		return cwd
+	}
 	if script.Scope != "" && script.Scope != "global" && script.Scope != cwd {
 		return script.Scope
 	}
@@ -329,29 +382,37 @@ func extractWorkingDirArg(args []string) (string, []string) {
 	return workingDir, remaining
 }
 
+// ExecutionWorkingDir returns the directory script runs in when started with
+// scriptArgs, and scriptArgs without --working-dir.
+func ExecutionWorkingDir(script *entities.Script, scriptArgs []string) (workingDir string, remaining []string, fromArgs bool) {
+	workingDirFromArgs, remaining := extractWorkingDirArg(scriptArgs)
+	if workingDirFromArgs != "" {
+		return workingDirFromArgs, remaining, true
+	}
+	return scriptDefaultWorkingDir(script), remaining, false
+}
+
 func (m *RootModel) showExecutionForm(script *entities.Script, scriptArgs []string, fromCLI bool) tea.Cmd {
 	return func() tea.Msg {
-		workingDirFromArgs, filteredArgs := extractWorkingDirArg(scriptArgs)
-		processingResult, err := m.container.ExecutionService.ProcessScriptArguments(script, filteredArgs)
-		if err != nil {
-			return ErrorMsg(fmt.Errorf("failed to process script arguments: %w", err))
-		}
+		workingDir, filteredArgs, workingDirFromArgs := ExecutionWorkingDir(script, scriptArgs)
 
-		workingDir := scriptDefaultWorkingDir(script)
-		if workingDirFromArgs != "" {
-			workingDir = workingDirFromArgs
+		processingResult, err := m.container.ExecutionService.ProcessScriptArguments(script, filteredArgs, workingDir)
+		if err != nil {
+			return errorOrUnlock(fmt.Errorf("failed to process script arguments: %w", err), m.showExecutionForm(script, scriptArgs, fromCLI))
 		}
 
-		canSkip := workingDirFromArgs != "" || script.OriginalScope != ""
+		canSkip := workingDirFromArgs || script.OriginalScope != ""
 		if !processingResult.NeedsPlaceholderForm && canSkip {
 			cwd, _ := os.Getwd()
 			finalCommand := processingResult.FinalCommand
 			if workingDir != "" && workingDir != cwd {
 				finalCommand = "cd " + shellQuote(workingDir) + " && " + finalCommand
 			}
-			record := m.buildHistoryRecord(script, finalCommand, processingResult.OriginalScript, processingResult.ParsedValues)
+			display := m.container.ExecutionService.RedactCommand(finalCommand)
+			secrets := m.container.ExecutionService.SecretValues(processingResult.OriginalScript, processingResult.ParsedValues)
+			record := m.buildHistoryRecord(script, display, processingResult.OriginalScript, processingResult.ParsedValues, secrets)
 			return ExecuteAppCommandMsg{
-				command:       m.container.TerminalService.PrepareScriptExecution(finalCommand, script.Name, processingResult.ParsedValues, workingDir, !fromCLI),
+				command:       m.container.TerminalService.PrepareScriptExecution(finalCommand, display, script.Name, processingResult.ParsedValues, secrets, workingDir, !fromCLI),
 				historyRecord: record,
 			}
 		}
@@ -373,9 +434,9 @@ func (m *RootModel) handleExecuteScript(script *entities.Script, scriptArgs []st
 func (m *RootModel) handleExecuteScriptWithDir(script *entities.Script, scriptArgs []string, workingDir string, writeHistory bool) tea.Cmd {
 	return func() tea.Msg {
 		log.Printf("handleExecuteScriptWithDir: scriptID=%q scriptName=%q workingDir=%q", script.ID, script.Name, workingDir)
-		processingResult, err := m.container.ExecutionService.ProcessScriptArguments(script, scriptArgs)
+		processingResult, err := m.container.ExecutionService.ProcessScriptArguments(script, scriptArgs, workingDir)
 		if err != nil {
-			return ErrorMsg(fmt.Errorf("failed to process script arguments: %w", err))
+			return errorOrUnlock(fmt.Errorf("failed to process script arguments: %w", err), m.handleExecuteScriptWithDir(script, scriptArgs, workingDir, writeHistory))
 		}
 
 		log.Printf("handleExecuteScriptWithDir: NeedsPlaceholderForm=%v", processingResult.NeedsPlaceholderForm)
@@ -388,10 +449,12 @@ func (m *RootModel) handleExecuteScriptWithDir(script *entities.Script, scriptAr
 			if workingDir != "" && workingDir != cwd {
 				finalCommand = "cd " + shellQuote(workingDir) + " && " + finalCommand
 			}
-			record := m.buildHistoryRecord(script, finalCommand, processingResult.OriginalScript, processingResult.ParsedValues)
+			display := m.container.ExecutionService.RedactCommand(finalCommand)
+			secrets := m.container.ExecutionService.SecretValues(processingResult.OriginalScript, processingResult.ParsedValues)
+			record := m.buildHistoryRecord(script, display, processingResult.OriginalScript, processingResult.ParsedValues, secrets)
 			log.Printf("handleExecuteScriptWithDir: historyRecord=%v", record != nil)
 			return ExecuteAppCommandMsg{
-				command:       m.container.TerminalService.PrepareScriptExecution(finalCommand, script.Name, processingResult.ParsedValues, workingDir, writeHistory),
+				command:       m.container.TerminalService.PrepareScriptExecution(finalCommand, display, script.Name, processingResult.ParsedValues, secrets, workingDir, writeHistory),
 				historyRecord: record,
 			}
 		}
@@ -402,9 +465,9 @@ func (m *RootModel) handleExecuteScriptWithDir(script *entities.Script, scriptAr
 
 func (m *RootModel) handleCopyScriptToClipboard(script *entities.Script) tea.Cmd {
 	return func() tea.Msg {
-		processingResult, err := m.container.ExecutionService.ProcessScriptArguments(script, []string{})
+		processingResult, err := m.container.ExecutionService.ProcessScriptArguments(script, []string{}, "")
 		if err != nil {
-			return ErrorMsg(fmt.Errorf("failed to process script arguments: %w", err))
+			return errorOrUnlock(fmt.Errorf("failed to process script arguments: %w", err), m.handleCopyScriptToClipboard(script))
 		}
 
 		if !processingResult.NeedsPlaceholderForm {
@@ -422,54 +485,85 @@ func (m *RootModel) handleCopyScriptToClipboard(script *entities.Script) tea.Cmd
 
 func (m *RootModel) finalizeExecute(script *entities.Script, values map[string]string, originalScript string, workingDir string) tea.Cmd {
 	return func() tea.Msg {
-		finalCommand, err := m.container.ExecutionService.PrepareExecution(script, []string{}, values)
+		finalCommand, err := m.container.ExecutionService.PrepareExecution(script, []string{}, values, workingDir)
 		if err != nil {
-			return ErrorMsg(fmt.Errorf("failed to prepare script execution: %w", err))
+			return errorOrUnlock(fmt.Errorf("failed to prepare script execution: %w", err), m.finalizeExecute(script, values, originalScript, workingDir))
 		}
 		cwd, _ := os.Getwd()
 		if workingDir != "" && workingDir != cwd {
 			finalCommand = "cd " + shellQuote(workingDir) + " && " + finalCommand
 		}
-		record := m.buildHistoryRecord(script, finalCommand, originalScript, values)
-		return ExecuteAppCommandMsg{command: m.container.TerminalService.PrepareScriptExecution(finalCommand, script.Name, values, workingDir, true), historyRecord: record}
+		display := m.container.ExecutionService.RedactCommand(finalCommand)
+		secrets := m.container.ExecutionService.SecretValues(originalScript, values)
+		record := m.buildHistoryRecord(script, display, originalScript, values, secrets)
+		return ExecuteAppCommandMsg{command: m.container.TerminalService.PrepareScriptExecution(finalCommand, display, script.Name, values, secrets, workingDir, true), historyRecord: record}
 	}
 }
 
 func (m *RootModel) finalizeCopy(script *entities.Script, values map[string]string) tea.Cmd {
 	return func() tea.Msg {
-		finalCommand, err := m.container.ExecutionService.PrepareExecution(script, []string{}, values)
+		finalCommand, err := m.container.ExecutionService.PrepareExecution(script, []string{}, values, "")
 		if err != nil {
-			return ErrorMsg(fmt.Errorf("failed to prepare command: %w", err))
+			return errorOrUnlock(fmt.Errorf("failed to prepare command: %w", err), m.finalizeCopy(script, values))
 		}
 		_ = clipboard.WriteAll(finalCommand)
 		return StatusMsg("Copied to clipboard")
 	}
 }
 
-func (m *RootModel) handleDeleteScript(script *entities.Script) tea.Cmd {
+func (m *RootModel) handleDeleteScript(scriptID string) tea.Cmd {
 	return func() tea.Msg {
-		if err := m.container.ScriptService.DeleteScript(script); err != nil {
-			return ErrorMsg(fmt.Errorf("error deleting script: %w", err))
+		script, err := m.container.ScriptService.GetScript(scriptID)
+		if err == nil {
+			err = m.container.ScriptService.DeleteScript(script)
 		}
-		return ScriptDeletedMsg{script: script}
+		if err != nil {
+			return ErrorMsg(fmt.Errorf("error moving script to trash: %w", err))
+		}
+		return ScriptDeletedMsg{scriptID: scriptID}
 	}
 }
 
-func (m *RootModel) handleArchiveScript(script *entities.Script) tea.Cmd {
+func (m *RootModel) handleArchiveScript(scriptID string) tea.Cmd {
 	return func() tea.Msg {
-		if err := m.container.ScriptService.ArchiveScript(script); err != nil {
+		script, err := m.container.ScriptService.GetScript(scriptID)
+		if err == nil {
+			err = m.container.ScriptService.ArchiveScript(script)
+		}
+		if err != nil {
 			return ErrorMsg(fmt.Errorf("error archiving script: %w", err))
 		}
-		return ScriptArchivedMsg{script: script}
+		return ScriptArchivedMsg{scriptID: scriptID}
 	}
 }
 
-func (m *RootModel) handleUnarchiveScript(script *entities.Script) tea.Cmd {
+func (m *RootModel) handleUnarchiveScript(scriptID string) tea.Cmd {
 	return func() tea.Msg {
-		if err := m.container.ScriptService.UnarchiveScript(script); err != nil {
+		script, err := m.container.ScriptService.GetScript(scriptID)
+		if err == nil {
+			err = m.container.ScriptService.UnarchiveScript(script)
+		}
+		if err != nil {
 			return ErrorMsg(fmt.Errorf("error unarchiving script: %w", err))
 		}
-		return ScriptUnarchivedMsg{script: script}
+		return ScriptUnarchivedMsg{scriptID: scriptID}
+	}
+}
+
+func (m *RootModel) handlePinScript(scriptID string, pinned bool) tea.Cmd {
+	return func() tea.Msg {
+		script, err := m.container.ScriptService.GetScript(scriptID)
+		if err == nil {
+			if pinned {
+				err = m.container.ScriptService.PinScript(script)
+			} else {
+				err = m.container.ScriptService.UnpinScript(script)
+			}
+		}
+		if err != nil {
+			return ErrorMsg(fmt.Errorf("error pinning script: %w", err))
+		}
+		return ScriptPinnedMsg{scriptID: scriptID, pinned: pinned}
 	}
 }
 
@@ -515,7 +609,16 @@ func (m *RootModel) GetPendingSavedCommand() string {
 	return m.pendingSavedCommand
 }
 
-func (m *RootModel) buildHistoryRecord(script *entities.Script, executedScript, originalScript string, placeholderValues map[string]string) *services.ExecutionRecord {
+// errorOrUnlock asks for the vault passphrase and then runs retry when err
+// comes from a locked vault, and reports err otherwise.
+func errorOrUnlock(err error, retry tea.Cmd) tea.Msg {
+	if errors.Is(err, services.ErrVaultLocked) {
+		return ShowVaultUnlockMsg{retry: retry}
+	}
+	return ErrorMsg(err)
+}
+
+func (m *RootModel) buildHistoryRecord(script *entities.Script, executedScript, originalScript string, placeholderValues, secrets map[string]string) *services.ExecutionRecord {
 	log.Printf("buildHistoryRecord: scriptID=%q scriptName=%q executedScript=%q", script.ID, script.Name, executedScript)
 	if script.ID == "" {
 		log.Printf("buildHistoryRecord: script.ID is empty, skipping history record (run --migrate to assign IDs)")
@@ -526,6 +629,7 @@ func (m *RootModel) buildHistoryRecord(script *entities.Script, executedScript,
 		placeholderValues = map[string]string{}
 	}
 	record := services.BuildExecutionRecord(script, executedScript, originalScript, placeholderValues, cwd)
+	record.Secrets = secrets
 	log.Printf("buildHistoryRecord: built record id=%q scriptID=%q", record.ID, record.ScriptID)
 	return &record
 }
@@ -99,6 +99,9 @@ func (s *ScopeEditChoiceScreen) copyToCurrent() tea.Cmd {
 	newScript := &entities.Script{
 		Name:          s.script.Name,
 		Description:   s.script.Description,
+		Tags:          s.script.Tags,
+		Aliases:       s.script.Aliases,
+		AutoQuote:     s.script.AutoQuote,
 		Scope:         cwd,
 		OriginalScope: "",
 	}
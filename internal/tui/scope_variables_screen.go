@@ -0,0 +1,284 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/vsuhanov/scripto/internal/services"
+	. "github.com/vsuhanov/scripto/internal/utils"
+)
+
+// ScopeVariablesScreen edits the variables of one scope, which prefill the
+// placeholders of the same name in every script run there.
+type ScopeVariablesScreen struct {
+	container *services.Container
+	scope     string
+	names     []string
+	variables map[string]string
+	width     int
+	height    int
+	err       error
+	statusMsg string
+	table     table.Model
+
+	editing       bool
+	originalName  string
+	nameInput     textinput.Model
+	valueInput    textinput.Model
+	confirmDelete bool
+}
+
+type scopeVariablesChangedMsg struct {
+	status string
+}
+
+func NewScopeVariablesScreen(container *services.Container, scope string, width, height int) *ScopeVariablesScreen {
+	nameInput := textinput.New()
+	nameInput.Placeholder = "Namespace"
+	nameInput.Width = 30
+	valueInput := textinput.New()
+	valueInput.Placeholder = "value"
+	valueInput.Width = 50
+
+	s := &ScopeVariablesScreen{
+		container:  container,
+		scope:      scope,
+		width:      width,
+		height:     height,
+		nameInput:  nameInput,
+		valueInput: valueInput,
+	}
+	s.load()
+	return s
+}
+
+func (s *ScopeVariablesScreen) load() {
+	s.variables = s.container.ScriptService.ScopeVariables(s.scope)
+	s.names = make([]string, 0, len(s.variables))
+	for name := range s.variables {
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+	s.table = s.buildTable()
+}
+
+func (s *ScopeVariablesScreen) buildTable() table.Model {
+	const nameWidth = 24
+	valueWidth := max(10, s.width-4-nameWidth-4)
+
+	rows := make([]table.Row, len(s.names))
+	for i, name := range s.names {
+		rows[i] = table.Row{TruncateString(name, nameWidth), TruncateString(s.variables[name], valueWidth)}
+	}
+
+	tableStyle := table.DefaultStyles()
+	tableStyle.Header = tableStyle.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(borderColor).
+		BorderBottom(true).
+		Bold(true).
+		Foreground(primaryColor)
+	tableStyle.Selected = tableStyle.Selected.
+		Foreground(selectedTextColor).
+		Background(selectedBgColor).
+		Bold(true)
+
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Name", Width: nameWidth},
+			{Title: "Value", Width: valueWidth},
+		}),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(max(3, min(len(rows)+1, s.height-8))),
+		table.WithStyles(tableStyle),
+	)
+	if s.table.Cursor() < len(rows) {
+		t.SetCursor(s.table.Cursor())
+	}
+	return t
+}
+
+func (s *ScopeVariablesScreen) selected() string {
+	if s.table.Cursor() < len(s.names) {
+		return s.names[s.table.Cursor()]
+	}
+	return ""
+}
+
+func (s *ScopeVariablesScreen) Init() tea.Cmd {
+	return nil
+}
+
+func (s *ScopeVariablesScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.width = msg.Width
+		s.height = msg.Height
+		s.table = s.buildTable()
+		return s, nil
+
+	case scopeVariablesChangedMsg:
+		s.statusMsg = msg.status
+		s.load()
+		return s, nil
+
+	case ErrorMsg:
+		s.statusMsg = ""
+		s.err = error(msg)
+		return s, nil
+
+	case tea.KeyMsg:
+		if s.editing {
+			return s.handleEditKey(msg)
+		}
+		return s.handleKey(msg)
+	}
+	return s, nil
+}
+
+func (s *ScopeVariablesScreen) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if s.confirmDelete {
+		s.confirmDelete = false
+		if msg.String() == "y" || msg.String() == "Y" {
+			return s, s.unset(s.selected())
+		}
+		s.statusMsg = "Cancelled"
+		return s, nil
+	}
+
+	s.err = nil
+	switch msg.String() {
+	case "q", "esc", "ctrl+c":
+		return s, func() tea.Msg { return NavigateBackMsg{} }
+
+	case "a":
+		return s, s.startEdit("")
+
+	case "e", "enter":
+		if name := s.selected(); name != "" {
+			return s, s.startEdit(name)
+		}
+		return s, nil
+
+	case "d":
+		if s.selected() != "" {
+			s.confirmDelete = true
+			s.statusMsg = ""
+		}
+		return s, nil
+
+	default:
+		var cmd tea.Cmd
+		s.table, cmd = s.table.Update(msg)
+		return s, cmd
+	}
+}
+
+func (s *ScopeVariablesScreen) startEdit(name string) tea.Cmd {
+	s.editing = true
+	s.originalName = name
+	s.nameInput.SetValue(name)
+	s.valueInput.SetValue(s.variables[name])
+	if name == "" {
+		s.valueInput.Blur()
+		return s.nameInput.Focus()
+	}
+	s.nameInput.Blur()
+	return s.valueInput.Focus()
+}
+
+func (s *ScopeVariablesScreen) handleEditKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		s.editing = false
+		return s, nil
+
+	case "tab", "shift+tab", "up", "down":
+		if s.nameInput.Focused() {
+			s.nameInput.Blur()
+			return s, s.valueInput.Focus()
+		}
+		s.valueInput.Blur()
+		return s, s.nameInput.Focus()
+
+	case "enter":
+		s.editing = false
+		return s, s.save(s.originalName, s.nameInput.Value(), s.valueInput.Value())
+	}
+
+	var cmd tea.Cmd
+	if s.nameInput.Focused() {
+		s.nameInput, cmd = s.nameInput.Update(msg)
+	} else {
+		s.valueInput, cmd = s.valueInput.Update(msg)
+	}
+	return s, cmd
+}
+
+func (s *ScopeVariablesScreen) save(originalName, name, value string) tea.Cmd {
+	scope := s.scope
+	return func() tea.Msg {
+		if err := s.container.ScriptService.SetScopeVariable(scope, name, value); err != nil {
+			return ErrorMsg(fmt.Errorf("failed to save variable: %w", err))
+		}
+		if originalName != "" && originalName != name {
+			if err := s.container.ScriptService.UnsetScopeVariable(scope, originalName); err != nil {
+				return ErrorMsg(fmt.Errorf("failed to rename variable: %w", err))
+			}
+		}
+		return scopeVariablesChangedMsg{status: fmt.Sprintf("Saved %s", name)}
+	}
+}
+
+func (s *ScopeVariablesScreen) unset(name string) tea.Cmd {
+	scope := s.scope
+	return func() tea.Msg {
+		if err := s.container.ScriptService.UnsetScopeVariable(scope, name); err != nil {
+			return ErrorMsg(fmt.Errorf("failed to remove variable: %w", err))
+		}
+		return scopeVariablesChangedMsg{status: fmt.Sprintf("Removed %s", name)}
+	}
+}
+
+func (s *ScopeVariablesScreen) View() string {
+	header := TitleStyle.Render(fmt.Sprintf("Variables for %s", s.scope))
+	parts := []string{header}
+
+	if len(s.names) == 0 {
+		parts = append(parts, NoScriptsStyle.Render("No variables. Press a to add one; placeholders with the same name are prefilled with it."))
+	} else {
+		parts = append(parts, ListStyle.Width(s.width-2).Render(s.table.View()))
+	}
+
+	if s.editing {
+		parts = append(parts,
+			"Name:  "+s.nameInput.View(),
+			"Value: "+s.valueInput.View(),
+		)
+	}
+
+	var footer string
+	switch {
+	case s.confirmDelete:
+		footer = ErrorStyle.Render(fmt.Sprintf("Remove '%s'? (y/n)", s.selected()))
+	case s.err != nil:
+		footer = ErrorStyle.Render(fmt.Sprintf("Error: %v", s.err))
+	case s.editing:
+		footer = HelpStyle.Render("tab: switch field • enter: save • esc: cancel")
+	default:
+		help := "j/k: navigate • a: add • e/enter: edit • d: remove • q/esc: back"
+		if s.statusMsg != "" {
+			help = s.statusMsg + " • " + help
+		}
+		footer = HelpStyle.Render(help)
+	}
+	parts = append(parts, footer)
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
@@ -0,0 +1,262 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/vsuhanov/scripto/internal/services"
+	. "github.com/vsuhanov/scripto/internal/utils"
+)
+
+// ScopesScreen lists the scopes that hold scripts and moves or merges them,
+// for when a project directory has been moved or renamed.
+type ScopesScreen struct {
+	container *services.Container
+	scopes    []services.ScopeInfo
+	width     int
+	height    int
+	err       error
+	statusMsg string
+	table     table.Model
+
+	editing bool
+	// merging is set while the destination is entered for a merge rather than
+	// a move.
+	merging     bool
+	targetInput textinput.Model
+}
+
+type scopesChangedMsg struct {
+	status string
+}
+
+func NewScopesScreen(container *services.Container, width, height int) *ScopesScreen {
+	targetInput := textinput.New()
+	targetInput.Placeholder = "New scope, e.g. /home/me/src/api"
+	targetInput.Width = 60
+
+	s := &ScopesScreen{
+		container:   container,
+		width:       width,
+		height:      height,
+		targetInput: targetInput,
+	}
+	s.load()
+	return s
+}
+
+func (s *ScopesScreen) load() {
+	scopes, err := s.container.ScriptService.ListScopes()
+	if err != nil {
+		s.err = err
+	}
+	s.scopes = scopes
+	s.table = s.buildTable()
+}
+
+func (s *ScopesScreen) buildTable() table.Model {
+	const kindWidth, scriptsWidth, existsWidth = 10, 8, 8
+	scopeWidth := max(20, s.width-4-kindWidth-scriptsWidth-existsWidth-8)
+
+	rows := make([]table.Row, len(s.scopes))
+	for i, scope := range s.scopes {
+		exists := "yes"
+		if !scope.Exists {
+			exists = "missing"
+		}
+		rows[i] = table.Row{
+			TruncateString(scope.Scope, scopeWidth),
+			scope.Kind,
+			strconv.Itoa(scope.Scripts),
+			exists,
+		}
+	}
+
+	tableStyle := table.DefaultStyles()
+	tableStyle.Header = tableStyle.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(borderColor).
+		BorderBottom(true).
+		Bold(true).
+		Foreground(primaryColor)
+	tableStyle.Selected = tableStyle.Selected.
+		Foreground(selectedTextColor).
+		Background(selectedBgColor).
+		Bold(true)
+
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Scope", Width: scopeWidth},
+			{Title: "Kind", Width: kindWidth},
+			{Title: "Scripts", Width: scriptsWidth},
+			{Title: "Exists", Width: existsWidth},
+		}),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(max(3, min(len(rows)+1, s.height-8))),
+		table.WithStyles(tableStyle),
+	)
+	if s.table.Cursor() < len(rows) {
+		t.SetCursor(s.table.Cursor())
+	}
+	return t
+}
+
+func (s *ScopesScreen) selected() string {
+	if s.table.Cursor() < len(s.scopes) {
+		return s.scopes[s.table.Cursor()].Scope
+	}
+	return ""
+}
+
+func (s *ScopesScreen) Init() tea.Cmd {
+	s.load()
+	return nil
+}
+
+func (s *ScopesScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.width = msg.Width
+		s.height = msg.Height
+		s.table = s.buildTable()
+		return s, nil
+
+	case scopesChangedMsg:
+		s.statusMsg = msg.status
+		s.load()
+		return s, nil
+
+	case ErrorMsg:
+		s.statusMsg = ""
+		s.err = error(msg)
+		return s, nil
+
+	case tea.KeyMsg:
+		if s.editing {
+			return s.handleEditKey(msg)
+		}
+		return s.handleKey(msg)
+	}
+	return s, nil
+}
+
+func (s *ScopesScreen) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s.err = nil
+	switch msg.String() {
+	case "q", "esc", "ctrl+c":
+		return s, func() tea.Msg { return NavigateBackMsg{} }
+
+	case "m", "enter":
+		return s, s.startEdit(false)
+
+	case "M":
+		return s, s.startEdit(true)
+
+	case "V":
+		if scope := s.selected(); scope != "" {
+			return s, func() tea.Msg { return ShowScopeVariablesMsg{scope: scope} }
+		}
+		return s, nil
+
+	default:
+		var cmd tea.Cmd
+		s.table, cmd = s.table.Update(msg)
+		return s, cmd
+	}
+}
+
+func (s *ScopesScreen) startEdit(merge bool) tea.Cmd {
+	scope := s.selected()
+	if scope == "" {
+		return nil
+	}
+	s.editing = true
+	s.merging = merge
+	s.statusMsg = ""
+	s.targetInput.SetValue(scope)
+	s.targetInput.CursorEnd()
+	return s.targetInput.Focus()
+}
+
+func (s *ScopesScreen) handleEditKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		s.editing = false
+		s.targetInput.Blur()
+		return s, nil
+
+	case "enter":
+		s.editing = false
+		s.targetInput.Blur()
+		return s, s.relocate(s.selected(), s.targetInput.Value(), s.merging)
+	}
+
+	var cmd tea.Cmd
+	s.targetInput, cmd = s.targetInput.Update(msg)
+	return s, cmd
+}
+
+func (s *ScopesScreen) relocate(from, to string, merge bool) tea.Cmd {
+	return func() tea.Msg {
+		to = services.ExpandScope(to)
+		if to != "global" && !services.IsMatchedScope(to) {
+			if abs, err := filepath.Abs(to); err == nil {
+				to = abs
+			}
+		}
+		var moved int
+		var err error
+		if merge {
+			moved, err = s.container.ScriptService.MergeScope(from, to)
+		} else {
+			moved, err = s.container.ScriptService.MoveScope(from, to)
+		}
+		if err != nil {
+			return ErrorMsg(fmt.Errorf("failed to move scope: %w", err))
+		}
+		return scopesChangedMsg{status: fmt.Sprintf("Moved %d scripts to %s", moved, to)}
+	}
+}
+
+func (s *ScopesScreen) View() string {
+	header := TitleStyle.Render("Scopes")
+	parts := []string{header}
+
+	if len(s.scopes) == 0 {
+		parts = append(parts, NoScriptsStyle.Render("No scopes with scripts or variables."))
+	} else {
+		parts = append(parts, ListStyle.Width(s.width-2).Render(s.table.View()))
+	}
+
+	if s.editing {
+		label := "Move to: "
+		if s.merging {
+			label = "Merge into: "
+		}
+		parts = append(parts, label+s.targetInput.View())
+	}
+
+	var footer string
+	switch {
+	case s.err != nil:
+		footer = ErrorStyle.Render(fmt.Sprintf("Error: %v", s.err))
+	case s.editing:
+		footer = HelpStyle.Render("enter: confirm • esc: cancel")
+	default:
+		help := "j/k: navigate • m/enter: move • M: merge • V: variables • q/esc: back"
+		if s.statusMsg != "" {
+			help = s.statusMsg + " • " + help
+		}
+		footer = HelpStyle.Render(help)
+	}
+	parts = append(parts, footer)
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
@@ -11,14 +11,19 @@ import (
 
 	"github.com/vsuhanov/scripto/entities"
 	"github.com/vsuhanov/scripto/internal/services"
+	"github.com/vsuhanov/scripto/internal/storage"
 )
 
 type ScriptEditorScreen struct {
 	nameInput        textinput.Model
 	descriptionInput textinput.Model
+	tagsInput        textinput.Model
+	aliasesInput     textinput.Model
 	commandTextarea  textarea.Model
 	scopeInput       textinput.Model
 	globalCheckbox   bool
+	projectCheckbox  bool
+	quoteCheckbox    bool
 
 	focusedField int
 	active       bool
@@ -37,12 +42,16 @@ type ScriptEditorScreen struct {
 const (
 	EditorScreenFieldName        = 0
 	EditorScreenFieldDescription = 1
-	EditorScreenFieldCommand     = 2
-	EditorScreenFieldGlobal      = 3
-	EditorScreenFieldScope       = 4
-	EditorScreenFieldSave        = 5
-	EditorScreenFieldCancel      = 6
-	EditorScreenFieldCount       = 7
+	EditorScreenFieldTags        = 2
+	EditorScreenFieldAliases     = 3
+	EditorScreenFieldCommand     = 4
+	EditorScreenFieldAutoQuote   = 5
+	EditorScreenFieldGlobal      = 6
+	EditorScreenFieldProject     = 7
+	EditorScreenFieldScope       = 8
+	EditorScreenFieldSave        = 9
+	EditorScreenFieldCancel      = 10
+	EditorScreenFieldCount       = 11
 )
 
 func NewScriptEditorScreen(script *entities.Script, isNewScript bool, container *services.Container) *ScriptEditorScreen {
@@ -60,14 +69,23 @@ func (e *ScriptEditorScreen) GetEditorValues() (name, description, command, scop
 	name = e.nameInput.Value()
 	description = e.descriptionInput.Value()
 	command = e.commandTextarea.Value()
-	if e.globalCheckbox {
+	if e.projectCheckbox {
+		scope = e.projectStore().Root
+	} else if e.globalCheckbox {
 		scope = "global"
 	} else {
-		scope = e.scopeInput.Value()
+		scope = services.ExpandScope(strings.TrimSpace(e.scopeInput.Value()))
 	}
 	return
 }
 
+func (e *ScriptEditorScreen) projectStore() *storage.ProjectStore {
+	if e.originalScript.Source != "" {
+		return storage.ProjectStoreFromConfigPath(e.originalScript.Source)
+	}
+	return e.container.ScriptService.ProjectTarget()
+}
+
 func (e *ScriptEditorScreen) SetErrorMessage(msg string) {
 	e.errorMessage = msg
 }
@@ -92,6 +110,18 @@ func (e *ScriptEditorScreen) initializeComponents() {
 	e.descriptionInput.CharLimit = 200
 	e.descriptionInput.Width = componentWidth
 
+	e.tagsInput = textinput.New()
+	e.tagsInput.Placeholder = "Tags, e.g. k8s, db"
+	e.tagsInput.SetValue(strings.Join(e.originalScript.Tags, ", "))
+	e.tagsInput.CharLimit = 200
+	e.tagsInput.Width = componentWidth
+
+	e.aliasesInput = textinput.New()
+	e.aliasesInput.Placeholder = "Other names, e.g. dep, ship"
+	e.aliasesInput.SetValue(strings.Join(e.originalScript.Aliases, ", "))
+	e.aliasesInput.CharLimit = 200
+	e.aliasesInput.Width = componentWidth
+
 	e.commandTextarea = textarea.New()
 	e.commandTextarea.Placeholder = "Enter command here..."
 
@@ -122,9 +152,11 @@ func (e *ScriptEditorScreen) initializeComponents() {
 	e.commandTextarea.SetHeight(6)
 
 	e.globalCheckbox = e.originalScript.Scope == "global"
+	e.projectCheckbox = e.originalScript.Source != ""
+	e.quoteCheckbox = e.originalScript.AutoQuote
 
 	e.scopeInput = textinput.New()
-	e.scopeInput.Placeholder = "Directory path or glob pattern"
+	e.scopeInput.Placeholder = "Directory path, glob pattern, git:host/org/repo or marker:FILE"
 	e.scopeInput.CharLimit = 500
 	e.scopeInput.Width = componentWidth
 
@@ -145,9 +177,19 @@ func (e *ScriptEditorScreen) initializeComponents() {
 	e.updateFocus()
 }
 
+func (e *ScriptEditorScreen) fieldHidden(field int) bool {
+	switch field {
+	case EditorScreenFieldGlobal:
+		return e.projectCheckbox
+	case EditorScreenFieldScope:
+		return e.globalCheckbox || e.projectCheckbox
+	}
+	return false
+}
+
 func (e *ScriptEditorScreen) nextField() int {
 	next := (e.focusedField + 1) % EditorScreenFieldCount
-	if next == EditorScreenFieldScope && e.globalCheckbox {
+	for e.fieldHidden(next) {
 		next = (next + 1) % EditorScreenFieldCount
 	}
 	return next
@@ -155,7 +197,7 @@ func (e *ScriptEditorScreen) nextField() int {
 
 func (e *ScriptEditorScreen) prevField() int {
 	prev := (e.focusedField - 1 + EditorScreenFieldCount) % EditorScreenFieldCount
-	if prev == EditorScreenFieldScope && e.globalCheckbox {
+	for e.fieldHidden(prev) {
 		prev = (prev - 1 + EditorScreenFieldCount) % EditorScreenFieldCount
 	}
 	return prev
@@ -188,6 +230,10 @@ func (e *ScriptEditorScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		e.nameInput, cmd = e.nameInput.Update(msg)
 	case EditorScreenFieldDescription:
 		e.descriptionInput, cmd = e.descriptionInput.Update(msg)
+	case EditorScreenFieldTags:
+		e.tagsInput, cmd = e.tagsInput.Update(msg)
+	case EditorScreenFieldAliases:
+		e.aliasesInput, cmd = e.aliasesInput.Update(msg)
 	case EditorScreenFieldCommand:
 		e.commandTextarea, cmd = e.commandTextarea.Update(msg)
 	case EditorScreenFieldScope:
@@ -215,6 +261,12 @@ func (e *ScriptEditorScreen) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		e.updateFocus()
 		return e, nil
 
+	case "ctrl+g":
+		if e.focusedField == EditorScreenFieldScope {
+			e.toggleGitScope()
+		}
+		return e, nil
+
 	case "enter":
 		if e.focusedField == EditorScreenFieldSave {
 			name, description, command, scope := e.GetEditorValues()
@@ -226,6 +278,16 @@ func (e *ScriptEditorScreen) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				e.errorMessage = "Scope is required"
 				return e, nil
 			}
+			tags, err := services.ParseTags(e.tagsInput.Value())
+			if err != nil {
+				e.errorMessage = err.Error()
+				return e, nil
+			}
+			aliases, err := services.NormalizeAliases(name, services.ParseAliases(e.aliasesInput.Value()))
+			if err != nil {
+				e.errorMessage = err.Error()
+				return e, nil
+			}
 			e.active = false
 			script := &entities.Script{
 				ID:          e.originalScript.ID,
@@ -233,6 +295,12 @@ func (e *ScriptEditorScreen) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				Description: description,
 				FilePath:    e.originalScript.FilePath,
 				Scope:       scope,
+				Tags:        tags,
+				Aliases:     aliases,
+				AutoQuote:   e.quoteCheckbox,
+			}
+			if e.projectCheckbox {
+				script.Source = e.projectStore().ConfigPath()
 			}
 			var original *entities.Script
 			if !e.isNewScript {
@@ -254,6 +322,13 @@ func (e *ScriptEditorScreen) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			e.globalCheckbox = !e.globalCheckbox
 			e.updateFocus()
 			return e, nil
+		} else if e.focusedField == EditorScreenFieldProject {
+			e.projectCheckbox = !e.projectCheckbox
+			e.updateFocus()
+			return e, nil
+		} else if e.focusedField == EditorScreenFieldAutoQuote {
+			e.quoteCheckbox = !e.quoteCheckbox
+			return e, nil
 		}
 		fallthrough
 
@@ -263,6 +338,15 @@ func (e *ScriptEditorScreen) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			e.updateFocus()
 			return e, nil
 		}
+		if e.focusedField == EditorScreenFieldProject {
+			e.projectCheckbox = !e.projectCheckbox
+			e.updateFocus()
+			return e, nil
+		}
+		if e.focusedField == EditorScreenFieldAutoQuote {
+			e.quoteCheckbox = !e.quoteCheckbox
+			return e, nil
+		}
 		fallthrough
 
 	default:
@@ -272,6 +356,10 @@ func (e *ScriptEditorScreen) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			e.nameInput, cmd = e.nameInput.Update(msg)
 		case EditorScreenFieldDescription:
 			e.descriptionInput, cmd = e.descriptionInput.Update(msg)
+		case EditorScreenFieldTags:
+			e.tagsInput, cmd = e.tagsInput.Update(msg)
+		case EditorScreenFieldAliases:
+			e.aliasesInput, cmd = e.aliasesInput.Update(msg)
 		case EditorScreenFieldCommand:
 			e.commandTextarea, cmd = e.commandTextarea.Update(msg)
 		case EditorScreenFieldScope:
@@ -281,9 +369,32 @@ func (e *ScriptEditorScreen) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	}
 }
 
+// toggleGitScope switches the scope field between the git repository of the
+// current directory and the current directory itself.
+func (e *ScriptEditorScreen) toggleGitScope() {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	gitScope, _, ok := services.GitScopeForDir(cwd)
+	if !ok {
+		e.errorMessage = "The current directory is not in a git repository with a remote"
+		return
+	}
+	e.errorMessage = ""
+	if e.scopeInput.Value() == gitScope {
+		e.scopeInput.SetValue(cwd)
+	} else {
+		e.scopeInput.SetValue(gitScope)
+	}
+	e.scopeInput.CursorEnd()
+}
+
 func (e *ScriptEditorScreen) updateFocus() {
 	e.nameInput.Blur()
 	e.descriptionInput.Blur()
+	e.tagsInput.Blur()
+	e.aliasesInput.Blur()
 	e.commandTextarea.Blur()
 	e.scopeInput.Blur()
 
@@ -292,10 +403,14 @@ func (e *ScriptEditorScreen) updateFocus() {
 		e.nameInput.Focus()
 	case EditorScreenFieldDescription:
 		e.descriptionInput.Focus()
+	case EditorScreenFieldTags:
+		e.tagsInput.Focus()
+	case EditorScreenFieldAliases:
+		e.aliasesInput.Focus()
 	case EditorScreenFieldCommand:
 		e.commandTextarea.Focus()
 	case EditorScreenFieldScope:
-		if !e.globalCheckbox {
+		if !e.globalCheckbox && !e.projectCheckbox {
 			e.scopeInput.Focus()
 		}
 	}
@@ -339,6 +454,20 @@ func (e *ScriptEditorScreen) View() string {
 	sections = append(sections, descLabel)
 	sections = append(sections, e.descriptionInput.View())
 
+	tagsLabel := FieldLabelStyle.Render("Tags:")
+	if e.focusedField == EditorScreenFieldTags {
+		tagsLabel = FieldLabelStyle.Foreground(primaryColor).Render("Tags:")
+	}
+	sections = append(sections, tagsLabel)
+	sections = append(sections, e.tagsInput.View())
+
+	aliasesLabel := FieldLabelStyle.Render("Aliases:")
+	if e.focusedField == EditorScreenFieldAliases {
+		aliasesLabel = FieldLabelStyle.Foreground(primaryColor).Render("Aliases:")
+	}
+	sections = append(sections, aliasesLabel)
+	sections = append(sections, e.aliasesInput.View())
+
 	cmdLabel := FieldLabelStyle.Render("Command:")
 	if e.focusedField == EditorScreenFieldCommand {
 		cmdLabel = FieldLabelStyle.Foreground(primaryColor).Render("Command:")
@@ -353,6 +482,17 @@ func (e *ScriptEditorScreen) View() string {
 	}
 	sections = append(sections, textareaView)
 
+	autoQuoteLabel := "☐ Auto-quote placeholder values"
+	autoQuoteStyle := FieldLabelStyle
+	if e.quoteCheckbox {
+		autoQuoteLabel = "☑ Auto-quote placeholder values"
+		autoQuoteStyle = FieldLabelStyle.Foreground(primaryColor)
+	}
+	if e.focusedField == EditorScreenFieldAutoQuote {
+		autoQuoteStyle = FieldLabelStyle.Foreground(primaryColor).Bold(true)
+	}
+	sections = append(sections, autoQuoteStyle.Render(autoQuoteLabel))
+
 	checkboxLabel := "☐ Global"
 	checkboxStyle := FieldLabelStyle
 	if e.globalCheckbox {
@@ -362,12 +502,25 @@ func (e *ScriptEditorScreen) View() string {
 	if e.focusedField == EditorScreenFieldGlobal {
 		checkboxStyle = FieldLabelStyle.Foreground(primaryColor).Bold(true)
 	}
-	sections = append(sections, checkboxStyle.Render(checkboxLabel))
+	if !e.projectCheckbox {
+		sections = append(sections, checkboxStyle.Render(checkboxLabel))
+	}
+
+	projectLabel := "☐ Project file"
+	projectStyle := FieldLabelStyle
+	if e.projectCheckbox {
+		projectLabel = "☑ Project file (" + e.projectStore().ConfigPath() + ")"
+		projectStyle = FieldLabelStyle.Foreground(primaryColor)
+	}
+	if e.focusedField == EditorScreenFieldProject {
+		projectStyle = FieldLabelStyle.Foreground(primaryColor).Bold(true)
+	}
+	sections = append(sections, projectStyle.Render(projectLabel))
 
-	if !e.globalCheckbox {
-		scopeLabel := FieldLabelStyle.Render("Scope (directory path or glob pattern):")
+	if !e.globalCheckbox && !e.projectCheckbox {
+		scopeLabel := FieldLabelStyle.Render("Scope (directory path, glob pattern or git repository):")
 		if e.focusedField == EditorScreenFieldScope {
-			scopeLabel = FieldLabelStyle.Foreground(primaryColor).Render("Scope (directory path or glob pattern):")
+			scopeLabel = FieldLabelStyle.Foreground(primaryColor).Render("Scope (directory path, glob pattern or git repository; ctrl+g: this repo):")
 		}
 		sections = append(sections, scopeLabel)
 		sections = append(sections, e.scopeInput.View())
@@ -86,6 +86,10 @@ var (
 				Foreground(colors.ScopeGlobal).
 				Bold(true)
 
+	ScopeMarkerStyle = lipgloss.NewStyle().
+				Foreground(colors.ScopeMarker).
+				Bold(true)
+
 	ScopeContextualStyle = lipgloss.NewStyle().
 				Foreground(warningColor).
 				Bold(true)
@@ -289,6 +293,8 @@ func GetScopeStyle(scope string) lipgloss.Style {
 		return ScopeParentStyle
 	case "global":
 		return ScopeGlobalStyle
+	case "marker":
+		return ScopeMarkerStyle
 	case "contextual":
 		return ScopeContextualStyle
 	default:
@@ -306,6 +312,8 @@ func FormatScopeIndicator(scope string) string {
 		return style.Render("◐")
 	case "global":
 		return style.Render("○")
+	case "marker":
+		return style.Render("▣")
 	case "contextual":
 		return style.Render("◆")
 	default:
@@ -329,6 +337,8 @@ func GetScopeColorHex(scope string) string {
 		c = colors.ScopeParent
 	case "global":
 		c = colors.ScopeGlobal
+	case "marker":
+		c = colors.ScopeMarker
 	default:
 		c = colors.ScopeOther
 	}
@@ -338,6 +348,15 @@ func GetScopeColorHex(scope string) string {
 	return string(c.Light.TrueColor)
 }
 
+// GetSectionColorHex returns the color of the pinned and tag sections in shell
+// completion.
+func GetSectionColorHex() string {
+	if lipgloss.HasDarkBackground() {
+		return string(colors.Primary.Dark.TrueColor)
+	}
+	return string(colors.Primary.Light.TrueColor)
+}
+
 func getScopeType(scope string) string {
 	if scope == "global" {
 		return "global"
@@ -348,7 +367,14 @@ func getScopeType(scope string) string {
 		return "other"
 	}
 
-	if services.IsPatternScope(scope) {
+	if services.IsMarkerScope(scope) {
+		if services.ScopeMatchesDir(scope, cwd) {
+			return "marker"
+		}
+		return "other"
+	}
+
+	if services.IsMatchedScope(scope) {
 		if services.ScopeMatchesDir(scope, cwd) {
 			return "local"
 		}
@@ -0,0 +1,323 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/vsuhanov/scripto/internal/services"
+	. "github.com/vsuhanov/scripto/internal/utils"
+)
+
+const (
+	trashConfirmNone = iota
+	trashConfirmPurge
+	trashConfirmPurgeAll
+)
+
+type TrashScreen struct {
+	container *services.Container
+	trash     []*services.TrashedScript
+	width     int
+	height    int
+	ready     bool
+	err       error
+	statusMsg string
+	table     table.Model
+	previewVP viewport.Model
+	confirm   int
+}
+
+type trashLoadedMsg struct {
+	trash []*services.TrashedScript
+}
+
+type trashChangedMsg struct {
+	status string
+}
+
+func NewTrashScreen(container *services.Container, width, height int) *TrashScreen {
+	return &TrashScreen{
+		container: container,
+		width:     width,
+		height:    height,
+		previewVP: viewport.New(max(1, width-4), 1),
+	}
+}
+
+func (s *TrashScreen) calcHeights() (tableHeight, previewHeight int) {
+	available := s.height - 8
+	tableHeight = max(3, min(len(s.trash)+1, available/2))
+	previewHeight = max(1, available-tableHeight-2)
+	return
+}
+
+func (s *TrashScreen) buildTable() table.Model {
+	tableH, _ := s.calcHeights()
+
+	const tsWidth = 16
+	const scopeWidth = 20
+	nameWidth := max(10, s.width-4-tsWidth-scopeWidth-6)
+
+	cols := []table.Column{
+		{Title: "Deleted", Width: tsWidth},
+		{Title: "Scope", Width: scopeWidth},
+		{Title: "Name", Width: nameWidth},
+	}
+
+	rows := make([]table.Row, len(s.trash))
+	for i, t := range s.trash {
+		name := t.Script.Name
+		if name == "" {
+			name = "Unnamed Script"
+		}
+		rows[i] = table.Row{
+			t.DeletedAt.Format("2006-01-02 15:04"),
+			TruncateString(t.Script.Scope, scopeWidth),
+			TruncateString(name, nameWidth),
+		}
+	}
+
+	tableStyle := table.DefaultStyles()
+	tableStyle.Header = tableStyle.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(borderColor).
+		BorderBottom(true).
+		Bold(true).
+		Foreground(primaryColor)
+	tableStyle.Selected = tableStyle.Selected.
+		Foreground(selectedTextColor).
+		Background(selectedBgColor).
+		Bold(true)
+
+	t := table.New(
+		table.WithColumns(cols),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(tableH),
+		table.WithStyles(tableStyle),
+	)
+	if s.table.Cursor() < len(rows) {
+		t.SetCursor(s.table.Cursor())
+	}
+	return t
+}
+
+func (s *TrashScreen) Init() tea.Cmd {
+	return s.loadTrash()
+}
+
+func (s *TrashScreen) loadTrash() tea.Cmd {
+	return func() tea.Msg {
+		trash, err := s.container.ScriptService.GetTrash()
+		if err != nil {
+			return ErrorMsg(fmt.Errorf("failed to load trash: %w", err))
+		}
+		return trashLoadedMsg{trash: trash}
+	}
+}
+
+func (s *TrashScreen) layout() {
+	_, previewH := s.calcHeights()
+	s.previewVP.Width = max(1, s.width-4)
+	s.previewVP.Height = previewH
+	s.table = s.buildTable()
+	s.updatePreview()
+}
+
+func (s *TrashScreen) selected() *services.TrashedScript {
+	if s.table.Cursor() < len(s.trash) {
+		return s.trash[s.table.Cursor()]
+	}
+	return nil
+}
+
+func (s *TrashScreen) updatePreview() {
+	t := s.selected()
+	if t == nil {
+		s.previewVP.SetContent("")
+		return
+	}
+	content := t.Body
+	if t.Script.Description != "" {
+		content = DescriptionStyle.Render(t.Script.Description) + "\n\n" + content
+	}
+	s.previewVP.SetContent(PreviewCommandStyle.Render(content))
+	s.previewVP.GotoTop()
+}
+
+func (s *TrashScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.width = msg.Width
+		s.height = msg.Height
+		if s.ready {
+			s.layout()
+		}
+		return s, nil
+
+	case trashLoadedMsg:
+		s.trash = msg.trash
+		s.ready = true
+		s.layout()
+		return s, nil
+
+	case trashChangedMsg:
+		s.statusMsg = msg.status
+		return s, s.loadTrash()
+
+	case ErrorMsg:
+		s.statusMsg = ""
+		s.err = error(msg)
+		s.ready = true
+		return s, nil
+
+	case tea.KeyMsg:
+		return s.handleKey(msg)
+	}
+
+	var cmd tea.Cmd
+	s.previewVP, cmd = s.previewVP.Update(msg)
+	return s, cmd
+}
+
+func (s *TrashScreen) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if s.confirm != trashConfirmNone {
+		switch msg.String() {
+		case "y", "Y":
+			confirm := s.confirm
+			s.confirm = trashConfirmNone
+			if confirm == trashConfirmPurgeAll {
+				return s, s.purgeAll()
+			}
+			return s, s.purge()
+		case "n", "N", "esc":
+			s.confirm = trashConfirmNone
+			s.statusMsg = "Cancelled"
+		}
+		return s, nil
+	}
+
+	s.err = nil
+	switch msg.String() {
+	case "q", "esc", "ctrl+c":
+		return s, func() tea.Msg { return NavigateBackMsg{} }
+
+	case "r":
+		if s.selected() != nil {
+			return s, s.restore()
+		}
+		return s, nil
+
+	case "p":
+		if s.selected() != nil {
+			s.confirm = trashConfirmPurge
+			s.statusMsg = ""
+		}
+		return s, nil
+
+	case "P":
+		if len(s.trash) > 0 {
+			s.confirm = trashConfirmPurgeAll
+			s.statusMsg = ""
+		}
+		return s, nil
+
+	case "ctrl+d", "pgdown":
+		s.previewVP.HalfViewDown()
+		return s, nil
+
+	case "ctrl+u", "pgup":
+		s.previewVP.HalfViewUp()
+		return s, nil
+
+	default:
+		var cmd tea.Cmd
+		s.table, cmd = s.table.Update(msg)
+		s.updatePreview()
+		return s, cmd
+	}
+}
+
+func (s *TrashScreen) restore() tea.Cmd {
+	id := s.selected().Script.ID
+	return func() tea.Msg {
+		script, err := s.container.ScriptService.RestoreScript(id)
+		if err != nil {
+			return ErrorMsg(fmt.Errorf("failed to restore script: %w", err))
+		}
+		return trashChangedMsg{status: fmt.Sprintf("Restored '%s'", script.Name)}
+	}
+}
+
+func (s *TrashScreen) purge() tea.Cmd {
+	t := s.selected()
+	if t == nil {
+		return nil
+	}
+	script := t.Script
+	return func() tea.Msg {
+		if err := s.container.ScriptService.PurgeScript(script); err != nil {
+			return ErrorMsg(fmt.Errorf("failed to purge script: %w", err))
+		}
+		return trashChangedMsg{status: fmt.Sprintf("Purged '%s'", script.Name)}
+	}
+}
+
+func (s *TrashScreen) purgeAll() tea.Cmd {
+	return func() tea.Msg {
+		purged, err := s.container.ScriptService.PurgeTrash()
+		if err != nil {
+			return ErrorMsg(fmt.Errorf("failed to empty trash: %w", err))
+		}
+		return trashChangedMsg{status: fmt.Sprintf("Purged %d scripts", purged)}
+	}
+}
+
+func (s *TrashScreen) View() string {
+	if !s.ready {
+		return LoadingStyle.Render("Loading trash...")
+	}
+
+	title := "Trash"
+	if retention := services.TrashRetention(); retention > 0 {
+		title = fmt.Sprintf("Trash (purged after %d days)", int(retention.Hours()/24))
+	}
+	header := TitleStyle.Render(title)
+
+	if len(s.trash) == 0 {
+		parts := []string{header, NoScriptsStyle.Render("Trash is empty.")}
+		if s.err != nil {
+			parts = append(parts, ErrorStyle.Render(fmt.Sprintf("Error: %v", s.err)))
+		}
+		if s.statusMsg != "" {
+			parts = append(parts, StatusStyle.Render(s.statusMsg))
+		}
+		parts = append(parts, HelpStyle.Render("q/esc: back"))
+		return lipgloss.JoinVertical(lipgloss.Left, parts...)
+	}
+
+	tablePane := ListStyle.Width(s.width - 2).Render(s.table.View())
+	previewPane := PreviewStyle.Width(s.width - 2).Render(s.previewVP.View())
+
+	var footer string
+	switch {
+	case s.confirm == trashConfirmPurge:
+		footer = ErrorStyle.Render(fmt.Sprintf("Permanently delete '%s'? (y/n)", s.selected().Script.Name))
+	case s.confirm == trashConfirmPurgeAll:
+		footer = ErrorStyle.Render(fmt.Sprintf("Permanently delete all %d scripts in the trash? (y/n)", len(s.trash)))
+	case s.err != nil:
+		footer = ErrorStyle.Render(fmt.Sprintf("Error: %v", s.err))
+	default:
+		help := "j/k: navigate • r: restore • p: purge • P: empty trash • ctrl+d/ctrl+u: scroll • q/esc: back"
+		if s.statusMsg != "" {
+			help = s.statusMsg + " • " + help
+		}
+		footer = HelpStyle.Render(help)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, tablePane, previewPane, footer)
+}
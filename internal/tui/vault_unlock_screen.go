@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/vsuhanov/scripto/internal/services"
+	"github.com/vsuhanov/scripto/internal/tui/colors"
+)
+
+type vaultUnlockFailedMsg struct {
+	err error
+}
+
+// VaultUnlockScreen asks for the vault passphrase when a script reads a secret
+// from a locked vault.
+type VaultUnlockScreen struct {
+	input     textinput.Model
+	retry     tea.Cmd
+	err       error
+	unlocking bool
+	width     int
+	height    int
+	container *services.Container
+}
+
+func NewVaultUnlockScreen(retry tea.Cmd, container *services.Container, width, height int) *VaultUnlockScreen {
+	input := textinput.New()
+	input.Width = 40
+	input.EchoMode = textinput.EchoPassword
+	input.Placeholder = "passphrase"
+	input.Focus()
+	return &VaultUnlockScreen{
+		input:     input,
+		retry:     retry,
+		width:     width,
+		height:    height,
+		container: container,
+	}
+}
+
+func (s *VaultUnlockScreen) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (s *VaultUnlockScreen) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		s.width = msg.Width
+		s.height = msg.Height
+		return s, nil
+
+	case vaultUnlockFailedMsg:
+		s.unlocking = false
+		s.err = msg.err
+		s.input.SetValue("")
+		return s, nil
+
+	case tea.KeyMsg:
+		if s.unlocking {
+			return s, nil
+		}
+		switch msg.String() {
+		case "esc":
+			return s, func() tea.Msg { return NavigateBackMsg{} }
+		case "enter":
+			s.unlocking = true
+			s.err = nil
+			return s, s.unlock(s.input.Value())
+		}
+	}
+
+	var cmd tea.Cmd
+	s.input, cmd = s.input.Update(msg)
+	return s, cmd
+}
+
+func (s *VaultUnlockScreen) unlock(passphrase string) tea.Cmd {
+	vault := s.container.VaultService
+	retry := s.retry
+	return func() tea.Msg {
+		if err := vault.Unlock(passphrase); err != nil {
+			return vaultUnlockFailedMsg{err: err}
+		}
+		return VaultUnlockedMsg{retry: retry}
+	}
+}
+
+func (s *VaultUnlockScreen) View() string {
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colors.Primary.Light.TrueColor)).
+		Bold(true).
+		MarginBottom(1)
+
+	subtitleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colors.MutedText.Dark.TrueColor))
+
+	hintStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(colors.MutedText.Dark.TrueColor)).
+		MarginTop(1)
+
+	lines := []string{
+		titleStyle.Render("Unlock Vault"),
+		subtitleStyle.Render("This script reads secrets from the vault."),
+		"",
+		s.input.View(),
+	}
+	if s.unlocking {
+		lines = append(lines, subtitleStyle.Render("Unlocking..."))
+	}
+	if s.err != nil {
+		lines = append(lines, ErrorStyle.Render(s.err.Error()))
+	}
+	lines = append(lines, hintStyle.Render("enter to unlock • esc to cancel"))
+
+	content := lipgloss.JoinVertical(lipgloss.Left, lines...)
+
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(colors.Border.Dark.TrueColor)).
+		Padding(1, 2)
+
+	return lipgloss.Place(s.width, s.height, lipgloss.Center, lipgloss.Center, boxStyle.Render(content))
+}
@@ -0,0 +1,114 @@
+package utils
+
+import "strings"
+
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffRemoved
+	DiffAdded
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// DiffRow is one row of a side-by-side diff. Left or Right is nil when the
+// line only exists on the other side.
+type DiffRow struct {
+	Left  *DiffLine
+	Right *DiffLine
+}
+
+// DiffLines computes a line diff of a and b using the longest common
+// subsequence.
+func DiffLines(a, b string) []DiffLine {
+	left := splitLines(a)
+	right := splitLines(b)
+
+	lcs := make([][]int, len(left)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(right)+1)
+	}
+	for i := len(left) - 1; i >= 0; i-- {
+		for j := len(right) - 1; j >= 0; j-- {
+			if left[i] == right[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var result []DiffLine
+	i, j := 0, 0
+	for i < len(left) && j < len(right) {
+		switch {
+		case left[i] == right[j]:
+			result = append(result, DiffLine{Op: DiffEqual, Text: left[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			result = append(result, DiffLine{Op: DiffRemoved, Text: left[i]})
+			i++
+		default:
+			result = append(result, DiffLine{Op: DiffAdded, Text: right[j]})
+			j++
+		}
+	}
+	for ; i < len(left); i++ {
+		result = append(result, DiffLine{Op: DiffRemoved, Text: left[i]})
+	}
+	for ; j < len(right); j++ {
+		result = append(result, DiffLine{Op: DiffAdded, Text: right[j]})
+	}
+	return result
+}
+
+// SideBySide pairs removed and added lines of a diff so that a change shows up
+// on the same row in both columns.
+func SideBySide(lines []DiffLine) []DiffRow {
+	var rows []DiffRow
+	var removed, added []*DiffLine
+
+	flush := func() {
+		for k := 0; k < len(removed) || k < len(added); k++ {
+			var row DiffRow
+			if k < len(removed) {
+				row.Left = removed[k]
+			}
+			if k < len(added) {
+				row.Right = added[k]
+			}
+			rows = append(rows, row)
+		}
+		removed, added = nil, nil
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		switch line.Op {
+		case DiffRemoved:
+			removed = append(removed, line)
+		case DiffAdded:
+			added = append(added, line)
+		default:
+			flush()
+			rows = append(rows, DiffRow{Left: line, Right: line})
+		}
+	}
+	flush()
+	return rows
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
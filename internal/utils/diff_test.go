@@ -0,0 +1,35 @@
+package utils
+
+import "testing"
+
+func TestDiffLines(t *testing.T) {
+	lines := DiffLines("a\nb\nc\n", "a\nc\nd\n")
+	expected := []DiffLine{
+		{Op: DiffEqual, Text: "a"},
+		{Op: DiffRemoved, Text: "b"},
+		{Op: DiffEqual, Text: "c"},
+		{Op: DiffAdded, Text: "d"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("line %d: expected %v, got %v", i, expected[i], lines[i])
+		}
+	}
+
+	if lines := DiffLines("", "x"); len(lines) != 1 || lines[0].Op != DiffAdded {
+		t.Errorf("expected a single added line, got %v", lines)
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	rows := SideBySide(DiffLines("a\nold\nz", "a\nnew\nz"))
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	if rows[1].Left == nil || rows[1].Left.Text != "old" || rows[1].Right == nil || rows[1].Right.Text != "new" {
+		t.Errorf("expected the change on one row, got %+v %+v", rows[1].Left, rows[1].Right)
+	}
+}
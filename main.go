@@ -2,19 +2,21 @@ package main
 
 import (
 	_ "embed"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"text/tabwriter"
 	"text/template"
 	"time"
 
 	"github.com/vsuhanov/scripto/entities"
 	"github.com/vsuhanov/scripto/internal/services"
-	"github.com/vsuhanov/scripto/internal/storage"
 	"github.com/vsuhanov/scripto/internal/templatex"
 	"github.com/vsuhanov/scripto/internal/tui"
 	"github.com/vsuhanov/scripto/internal/tui/colors"
@@ -98,11 +100,20 @@ func handleCommand(container *services.Container, args []string) {
 		return
 	}
 
+	if len(args) > 0 && args[0] == "doctor" {
+		os.Exit(handleDoctor(container, args[1:]))
+	}
+
+	if len(args) > 0 && args[0] == "secret" {
+		os.Exit(handleSecret(container, args[1:]))
+	}
+
 	if len(args) > 0 && args[0] == "cli" {
 		os.Exit(handleCli(container, args[1:]))
 	}
 
 	if len(args) == 0 {
+		purgeExpiredTrash(container)
 		if err := tui.RunApp(container, tui.ShowMainListRequest{}); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err.Error())
 			container.TerminalService.ExecuteCommand(container.TerminalService.PrepareExit(1))
@@ -111,6 +122,7 @@ func handleCommand(container *services.Container, args []string) {
 	}
 
 	if args[0] == "add" {
+		purgeExpiredTrash(container)
 		if err := tui.RunApp(container, tui.ShowAddScreenRequest{}); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err.Error())
 			container.TerminalService.ExecuteCommand(container.TerminalService.PrepareExit(1))
@@ -125,19 +137,24 @@ func handleCommand(container *services.Container, args []string) {
 	}
 }
 
-func runMigrate() error {
-	configPath, err := storage.GetConfigPath()
-	if err != nil {
-		return fmt.Errorf("failed to get config path: %w", err)
+// purgeExpiredTrash removes the scripts kept in the trash for longer than
+// the retention. It runs where scripts are browsed or changed rather than on
+// every start, so shell completion never writes to the database.
+func purgeExpiredTrash(container *services.Container) {
+	if _, err := container.ScriptService.PurgeExpiredTrash(); err != nil {
+		log.Printf("Warning: %v", err)
 	}
-	config, err := storage.ReadConfig(configPath)
+}
+
+// runMigrate is kept for existing muscle memory; it is 'scripto doctor --fix'.
+func runMigrate() error {
+	container, err := services.NewContainer()
 	if err != nil {
-		return fmt.Errorf("failed to read config: %w", err)
+		return err
 	}
-	if err := storage.WriteConfig(configPath, config); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
+	if code := handleDoctor(container, []string{"--fix"}); code != 0 {
+		return fmt.Errorf("some problems could not be fixed")
 	}
-	fmt.Println("Migration complete")
 	return nil
 }
 
@@ -333,13 +350,24 @@ func isValidAliasName(name string) bool {
 
 func executeScript(container *services.Container, userArgs []string) error {
 	scriptName, scriptArgs := parseScriptNameAndArgs(userArgs)
+	separated := slices.Contains(userArgs, "--")
 
 	matchResult, err := container.ScriptService.Match(scriptName)
 	if err != nil {
 		return fmt.Errorf("failed to match script: %w", err)
 	}
 
+	if matchResult == nil && !separated {
+		matchResult, scriptArgs, err = matchLeadingName(container, userArgs)
+		if err != nil {
+			return err
+		}
+	}
+
 	if matchResult != nil {
+		if !separated && len(scriptArgs) == 1 && scriptArgs[0] == "--help" {
+			return printScriptUsage(matchResult)
+		}
 		return executeFoundScript(container, matchResult, scriptArgs)
 	}
 
@@ -357,8 +385,7 @@ func executeScript(container *services.Container, userArgs []string) error {
 	}
 
 	if len(allScopeMatches) == 1 {
-		script := allScopeMatches[0]
-		markContextualIfApplicable(container, script)
+		script := markContextualIfApplicable(container, allScopeMatches[0])
 		return executeFoundScript(container, script, scriptArgs)
 	}
 
@@ -394,29 +421,140 @@ func parseScriptNameAndArgs(userArgs []string) (string, []string) {
 	return scriptName, scriptArgs
 }
 
+// matchLeadingName finds the script named by the longest run of leading
+// arguments shorter than all of them, so 'scripto deploy app.zip prod' runs
+// deploy with two arguments.
+func matchLeadingName(container *services.Container, userArgs []string) (*entities.Script, []string, error) {
+	for n := len(userArgs) - 1; n > 0; n-- {
+		script, err := container.ScriptService.Match(strings.Join(userArgs[:n], " "))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to match script: %w", err)
+		}
+		if script != nil {
+			return script, userArgs[n:], nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// printScriptUsage prints how to pass the placeholders of script as
+// arguments, for 'scripto <name> --help'.
+func printScriptUsage(script *entities.Script) error {
+	content, err := os.ReadFile(script.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read script file %s: %w", script.FilePath, err)
+	}
+	var metas []templatex.VariableMeta
+	if !strings.HasPrefix(string(content), "#!") {
+		if metas, err = templatex.ExtractVariables(strings.TrimSpace(string(content))); err != nil {
+			return fmt.Errorf("failed to parse template: %w", err)
+		}
+	}
+	ordered, err := templatex.PositionalOrder(metas)
+	if err != nil {
+		return err
+	}
+
+	if len(ordered) == 0 {
+		fmt.Printf("Usage: scripto %s [--] [ARGS...]\n", script.Name)
+	} else {
+		positional := make([]string, len(ordered))
+		named := make([]string, len(ordered))
+		for i, meta := range ordered {
+			positional[i] = "<" + meta.Name + ">"
+			named[i] = "--" + meta.Name + "=VALUE"
+			if meta.DefaultValue != "" {
+				positional[i] = "[" + positional[i] + "]"
+				named[i] = "[" + named[i] + "]"
+			}
+		}
+		fmt.Printf("Usage: scripto %s [--] %s\n", script.Name, strings.Join(positional, " "))
+		fmt.Printf("       scripto %s -- %s\n", script.Name, strings.Join(named, " "))
+	}
+	if script.Description != "" {
+		fmt.Printf("\n%s\n", script.Description)
+	}
+	if len(ordered) == 0 {
+		fmt.Println("\nArguments are passed on to the command.")
+		return nil
+	}
+
+	fmt.Println("\nArguments:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, meta := range ordered {
+		var details []string
+		if meta.Label != meta.Name {
+			details = append(details, meta.Label)
+		}
+		if meta.Type != "" {
+			details = append(details, "type: "+meta.Type)
+		}
+		if meta.Pattern != "" {
+			details = append(details, "pattern: "+meta.Pattern)
+		}
+		if len(meta.AllowedValues) > 0 {
+			details = append(details, "one of: "+strings.Join(meta.AllowedValues, ", "))
+		}
+		if meta.AllowedValuesFrom != "" {
+			details = append(details, "values from: "+meta.AllowedValuesFrom)
+		}
+		if meta.Secret {
+			details = append(details, "secret")
+		} else if meta.DefaultValue != "" {
+			details = append(details, "default: "+meta.DefaultValue)
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%s\n", i+1, meta.Name, strings.Join(details, "; "))
+	}
+	return w.Flush()
+}
+
+// runApp starts the TUI; tests replace it.
+var runApp = tui.RunApp
+
 func executeFoundScript(container *services.Container, scriptEnt *entities.Script, scriptArgs []string) error {
-	return tui.RunApp(container, tui.ExecuteScriptRequest{Script: scriptEnt, ScriptArgs: scriptArgs})
+	if err := checkScriptArgs(container, scriptEnt, scriptArgs); err != nil {
+		return err
+	}
+	return runApp(container, tui.ExecuteScriptRequest{Script: scriptEnt, ScriptArgs: scriptArgs})
 }
 
-func markContextualIfApplicable(container *services.Container, script *entities.Script) {
+// checkScriptArgs fails a CLI run before the TUI starts when a placeholder
+// value does not pass its type or pattern annotation. Other problems, such as
+// a locked vault, are left to the TUI, which can resolve them.
+func checkScriptArgs(container *services.Container, script *entities.Script, scriptArgs []string) error {
+	workingDir, args, _ := tui.ExecutionWorkingDir(script, scriptArgs)
+	_, err := container.ExecutionService.ProcessScriptArguments(script, args, workingDir)
+	var invalid *services.InvalidValueError
+	if errors.As(err, &invalid) {
+		return fmt.Errorf("%s: %w", invalid.Name, invalid.Err)
+	}
+	return nil
+}
+
+// markContextualIfApplicable returns a copy of script scoped to the working
+// directory when it was run from there before. The stored script is left
+// untouched.
+func markContextualIfApplicable(container *services.Container, script *entities.Script) *entities.Script {
 	if container.ExecutionHistoryService == nil || script.ID == "" {
-		return
+		return script
 	}
 	cwd, err := os.Getwd()
 	if err != nil {
-		return
+		return script
 	}
 	ids, err := container.ExecutionHistoryService.GetScriptIDsRunFromDirectory(cwd)
 	if err != nil {
-		return
+		return script
 	}
 	for _, id := range ids {
 		if id == script.ID {
-			script.OriginalScope = script.Scope
-			script.Scope = cwd
-			return
+			contextual := *script
+			contextual.OriginalScope = script.Scope
+			contextual.Scope = cwd
+			return &contextual
 		}
 	}
+	return script
 }
 
 
@@ -429,10 +567,15 @@ func handleCompletion(container *services.Container, args []string) {
 	}
 
 	showAll := false
-	for _, arg := range args {
-		if arg == "--more" {
+	groupBy := "tag"
+	for i, arg := range args {
+		switch arg {
+		case "--more":
 			showAll = true
-			break
+		case "--group-by":
+			if i+1 < len(args) {
+				groupBy = args[i+1]
+			}
 		}
 	}
 	// var toComplete string
@@ -450,14 +593,18 @@ func handleCompletion(container *services.Container, args []string) {
 	// 	cleanToComplete = strings.TrimPrefix(cleanToComplete, "\"")
 	// }
 
-	suggestions := getCompletionSuggestions(container, showAll)
+	suggestions := getCompletionSuggestions(container, showAll, groupBy)
 
 	for _, suggestion := range suggestions {
 		fmt.Println(suggestion)
 	}
 }
 
-func getCompletionSuggestions(container *services.Container, showAll bool) []string {
+// getCompletionSuggestions lists scripts in completion sections. Pinned scripts
+// come first in their own section. With groupBy "tag", tagged scripts are
+// listed once per tag in '#tag' sections and only untagged scripts are grouped
+// by scope.
+func getCompletionSuggestions(container *services.Container, showAll bool, groupBy string) []string {
 	separator := "\x1F"
 
 	var allScripts []*entities.Script
@@ -479,6 +626,48 @@ func getCompletionSuggestions(container *services.Container, showAll bool) []str
 		frecencyScores = map[string]float64{}
 	}
 
+	var suggestions []string
+	var pinned, unpinned []*entities.Script
+	for _, script := range allScripts {
+		if script.Pinned {
+			pinned = append(pinned, script)
+		} else {
+			unpinned = append(unpinned, script)
+		}
+	}
+	sort.SliceStable(pinned, func(i, j int) bool {
+		return frecencyScores[pinned[i].ID] > frecencyScores[pinned[j].ID]
+	})
+	suggestions = append(suggestions, convertScriptResultsToSuggestionsInGroup(pinned, "Pinned", tui.GetSectionColorHex(), separator)...)
+	allScripts = unpinned
+
+	if groupBy == "tag" {
+		var untagged []*entities.Script
+		tagScripts := make(map[string][]*entities.Script)
+		for _, script := range allScripts {
+			if len(script.Tags) == 0 {
+				untagged = append(untagged, script)
+				continue
+			}
+			for _, tag := range script.Tags {
+				tagScripts[tag] = append(tagScripts[tag], script)
+			}
+		}
+		tags := make([]string, 0, len(tagScripts))
+		for tag := range tagScripts {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		for _, tag := range tags {
+			scripts := tagScripts[tag]
+			sort.SliceStable(scripts, func(i, j int) bool {
+				return frecencyScores[scripts[i].ID] > frecencyScores[scripts[j].ID]
+			})
+			suggestions = append(suggestions, convertScriptResultsToSuggestionsInGroup(scripts, "#"+tag, tui.GetSectionColorHex(), separator)...)
+		}
+		allScripts = untagged
+	}
+
 	var scopeOrder []string
 	scopeScripts := make(map[string][]*entities.Script)
 	for _, script := range allScripts {
@@ -499,7 +688,7 @@ func getCompletionSuggestions(container *services.Container, showAll bool) []str
 		sorted = append(sorted, scopeScripts[scope]...)
 	}
 
-	return convertScriptResultsToSuggestions(sorted, separator)
+	return append(suggestions, convertScriptResultsToSuggestions(sorted, separator)...)
 }
 
 func getArgsCompletionSuggestions(container *services.Container, scriptName string) []string {
@@ -526,10 +715,15 @@ func getArgsCompletionSuggestions(container *services.Container, scriptName stri
 	}
 
 	var keyOrder []string
+	secret := map[string]bool{}
 	if script.FilePath != "" {
 		if content, rErr := os.ReadFile(script.FilePath); rErr == nil {
 			if metas, mErr := templatex.ExtractVariables(strings.TrimSpace(string(content))); mErr == nil {
 				for _, meta := range metas {
+					if meta.Secret {
+						secret[meta.Name] = true
+						continue
+					}
 					keyOrder = append(keyOrder, meta.Name)
 				}
 			}
@@ -539,7 +733,13 @@ func getArgsCompletionSuggestions(container *services.Container, scriptName stri
 	seen := map[string]bool{}
 	var suggestions []string
 	for _, record := range records {
-		argsStr := buildArgsString(record.PlaceholderValues, keyOrder)
+		values := make(map[string]string, len(record.PlaceholderValues))
+		for key, val := range record.PlaceholderValues {
+			if !secret[key] {
+				values[key] = val
+			}
+		}
+		argsStr := buildArgsString(values, keyOrder)
 		if argsStr == "" || seen[argsStr] {
 			continue
 		}
@@ -581,6 +781,31 @@ func shellQuoteValue(val string) string {
 	return "'" + strings.ReplaceAll(val, "'", `'\''`) + "'"
 }
 
+// convertScriptResultsToSuggestionsInGroup formats scripts like
+// convertScriptResultsToSuggestions, but under a single group and color.
+func convertScriptResultsToSuggestionsInGroup(results []*entities.Script, group, color, separator string) []string {
+	var suggestions []string
+	for _, script := range results {
+		if script.Name != "" {
+			suggestions = append(suggestions, group+separator+script.Name+separator+script.Description+separator+color)
+			for _, alias := range script.Aliases {
+				suggestions = append(suggestions, group+separator+alias+separator+aliasDescription(script)+separator+color)
+			}
+		} else {
+			suggestions = append(suggestions, group+separator+script.FilePath+separator+script.FilePath+separator+color)
+		}
+	}
+	return suggestions
+}
+
+// aliasDescription describes an alias completion by the script it runs.
+func aliasDescription(script *entities.Script) string {
+	if script.Description == "" {
+		return "alias of " + script.Name
+	}
+	return "alias of " + script.Name + ": " + script.Description
+}
+
 func convertScriptResultsToSuggestions(results []*entities.Script, separator string) []string {
 	var suggestions []string
 	for _, script := range results {
@@ -593,6 +818,9 @@ func convertScriptResultsToSuggestions(results []*entities.Script, separator str
 			name := script.Name
 			scopeColor := tui.GetScopeColorHex(script.Scope)
 			suggestions = append(suggestions, script.Scope+separator+name+separator+description+separator+scopeColor)
+			for _, alias := range script.Aliases {
+				suggestions = append(suggestions, script.Scope+separator+alias+separator+aliasDescription(script)+separator+scopeColor)
+			}
 		} else {
 			command := script.FilePath
 			displayCommand := command
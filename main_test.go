@@ -0,0 +1,71 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/vsuhanov/scripto/entities"
+	"github.com/vsuhanov/scripto/internal/services"
+	"github.com/vsuhanov/scripto/internal/tui"
+)
+
+func newTestContainer(t *testing.T) *services.Container {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("SCRIPTO_CONFIG", filepath.Join(dir, "scripts.json"))
+	t.Setenv("SCRIPTO_SQLITE_DB_PATH", "")
+	t.Chdir(dir)
+
+	container, err := services.NewContainer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return container
+}
+
+func TestExecuteFoundScript_RejectsInvalidValues(t *testing.T) {
+	container := newTestContainer(t)
+	script := &entities.Script{Name: "deploy", Scope: "global"}
+	command := `deploy -n {{ .N | type "int" }} --url {{ .Url | type "url" }} --env {{ .Env | pattern "[a-z]+" }}`
+	if err := container.ScriptService.SaveScript(script, command, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	started := false
+	saved := runApp
+	t.Cleanup(func() { runApp = saved })
+	runApp = func(*services.Container, tui.TuiRequest) error {
+		started = true
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"int", []string{"--N=abc", "--Url=https://example.com", "--Env=prod"}, "N: 'abc' is not a whole number"},
+		{"url", []string{"--N=3", "--Url=example.com", "--Env=prod"}, "Url: 'example.com' is not a URL, e.g. https://example.com"},
+		{"pattern", []string{"--N=3", "--Url=https://example.com", "--Env=Prod1"}, "Env: 'Prod1' does not match [a-z]+"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started = false
+			err := executeFoundScript(container, script, tt.args)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected %q, got %v", tt.wantErr, err)
+			}
+			if started {
+				t.Errorf("expected the TUI not to start")
+			}
+		})
+	}
+
+	if err := executeFoundScript(container, script, []string{"--N=3", "--Url=https://example.com", "--Env=prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if !started {
+		t.Errorf("expected valid values to start the TUI")
+	}
+}
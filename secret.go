@@ -0,0 +1,297 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	xterm "github.com/charmbracelet/x/term"
+
+	"github.com/vsuhanov/scripto/internal/services"
+	"github.com/vsuhanov/scripto/internal/storage"
+)
+
+const secretUsage = `Usage: scripto secret <command> [flags]
+
+Keeps secrets in an encrypted vault in the scripto data directory. Scripts read
+them with {{ secret "NAME" }}; a secret scoped to the current directory wins
+over a pattern, parent directory or global secret with the same name.
+
+Commands:
+  set NAME [--scope S] [--value V | --stdin]   store a secret (prompts for the value)
+  get NAME [--scope S]                         print the secret that applies here
+  list [--json]                                list secret names and scopes
+  rm NAME [--scope S]                          remove a secret
+
+Flags:
+  --scope S   'global', an absolute directory path, a glob pattern,
+              git:host/org/repo, or marker:FILE
+              (default: current directory; get defaults to resolving here)
+
+The passphrase is read from $` + services.VaultPassphraseEnv + ` or prompted for.`
+
+type secretListEntry struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+// handleSecret runs a vault command and returns the process exit code.
+func handleSecret(container *services.Container, args []string) int {
+	if len(args) == 0 || args[0] == "--help" || args[0] == "-h" {
+		fmt.Println(secretUsage)
+		if len(args) == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	var err error
+	switch args[0] {
+	case "set":
+		err = secretSet(container.VaultService, args[1:])
+	case "get":
+		err = secretGet(container.VaultService, args[1:])
+	case "list", "ls":
+		err = secretList(container.VaultService, args[1:])
+	case "rm", "remove":
+		err = secretRemove(container.VaultService, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown secret command '%s'\n\n%s\n", args[0], secretUsage)
+		return 1
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Println(secretUsage)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// parseSecretArgs parses flags and returns the secret name, which may come
+// before or after them.
+func parseSecretArgs(fs *flag.FlagSet, args []string) (string, error) {
+	name := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if name == "" && fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+	if name == "" {
+		return "", fmt.Errorf("secret name is required")
+	}
+	return name, nil
+}
+
+func secretScope(scope string) (string, error) {
+	if scope == "" {
+		return os.Getwd()
+	}
+	return cliScopeArg(scope)
+}
+
+func secretSet(vault *services.VaultService, args []string) error {
+	fs := flag.NewFlagSet("set", flag.ContinueOnError)
+	scope := fs.String("scope", "", "")
+	value := fs.String("value", "", "")
+	useStdin := fs.Bool("stdin", false, "")
+	name, err := parseSecretArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	scopeValue, err := secretScope(*scope)
+	if err != nil {
+		return fmt.Errorf("failed to resolve scope: %w", err)
+	}
+
+	if err := unlockVault(vault, true); err != nil {
+		return err
+	}
+
+	secretValue := *value
+	switch {
+	case *useStdin:
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		secretValue = strings.TrimRight(string(data), "\r\n")
+	case secretValue == "":
+		secretValue, err = promptHidden(fmt.Sprintf("Value for %s: ", name))
+		if err != nil {
+			return err
+		}
+	}
+	if secretValue == "" {
+		return fmt.Errorf("secret value cannot be empty")
+	}
+
+	if err := vault.Set(name, scopeValue, secretValue); err != nil {
+		return fmt.Errorf("failed to save secret: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Saved secret %s in scope %s\n", name, scopeValue)
+	return nil
+}
+
+func secretGet(vault *services.VaultService, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	scope := fs.String("scope", "", "")
+	name, err := parseSecretArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := unlockVault(vault, false); err != nil {
+		return err
+	}
+
+	if *scope == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		value, err := vault.Resolve(name, cwd)
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	}
+
+	scopeValue, err := secretScope(*scope)
+	if err != nil {
+		return fmt.Errorf("failed to resolve scope: %w", err)
+	}
+	secret, err := vault.Get(name, scopeValue)
+	if err != nil {
+		return err
+	}
+	fmt.Println(secret.Value)
+	return nil
+}
+
+func secretList(vault *services.VaultService, args []string) error {
+	asJSON := false
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			asJSON = true
+		case "--help", "-h":
+			return flag.ErrHelp
+		default:
+			return fmt.Errorf("unknown flag '%s'", arg)
+		}
+	}
+	if err := unlockVault(vault, false); err != nil {
+		return err
+	}
+	secrets, err := vault.List()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]secretListEntry, 0, len(secrets))
+	for _, secret := range secrets {
+		entries = append(entries, secretListEntry{Name: secret.Name, Scope: secret.Scope})
+	}
+	if asJSON {
+		printJSON(entries)
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Println("No secrets")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCOPE")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\n", entry.Name, entry.Scope)
+	}
+	return w.Flush()
+}
+
+func secretRemove(vault *services.VaultService, args []string) error {
+	fs := flag.NewFlagSet("rm", flag.ContinueOnError)
+	scope := fs.String("scope", "", "")
+	name, err := parseSecretArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	scopeValue, err := secretScope(*scope)
+	if err != nil {
+		return fmt.Errorf("failed to resolve scope: %w", err)
+	}
+	if err := unlockVault(vault, false); err != nil {
+		return err
+	}
+	if err := vault.Remove(name, scopeValue); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Removed secret %s from scope %s\n", name, scopeValue)
+	return nil
+}
+
+// unlockVault opens the vault with the passphrase from the environment or a
+// prompt. With create set, a missing vault gets a new, confirmed passphrase.
+func unlockVault(vault *services.VaultService, create bool) error {
+	if passphrase := os.Getenv(services.VaultPassphraseEnv); passphrase != "" {
+		return vault.Unlock(passphrase)
+	}
+	if !vault.Exists() {
+		if !create {
+			return nil
+		}
+		passphrase, err := promptHidden("New vault passphrase: ")
+		if err != nil {
+			return err
+		}
+		confirm, err := promptHidden("Repeat passphrase: ")
+		if err != nil {
+			return err
+		}
+		if passphrase != confirm {
+			return fmt.Errorf("passphrases do not match")
+		}
+		return vault.Unlock(passphrase)
+	}
+
+	passphrase, err := promptHidden("Vault passphrase: ")
+	if err != nil {
+		return err
+	}
+	if err := vault.Unlock(passphrase); err != nil {
+		if errors.Is(err, storage.ErrWrongPassphrase) {
+			return err
+		}
+		return fmt.Errorf("failed to unlock vault: %w", err)
+	}
+	return nil
+}
+
+// promptHidden reads a line from the terminal without echoing it, so stdin
+// stays free for --stdin.
+func promptHidden(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal to prompt on; set %s", services.VaultPassphraseEnv)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	data, err := xterm.ReadPassword(tty.Fd())
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return string(data), nil
+}